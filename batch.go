@@ -0,0 +1,118 @@
+package sqlstruct
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// maxBatchRows limits the number of rows inserted using a single statement.
+const maxBatchRows = 1000
+
+// InsertAll inserts all elements of src, a slice of structs or of pointers to
+// structs, using multi-row INSERT statements. Statements are split to respect
+// the parameter limit of the dialect, and between rows omitting different
+// columns (see Insert). Generated primary keys are written back into each
+// element in order. Dialects reading back keys using LastInsertId insert rows
+// with generated keys one at a time, and dialects using OUTPUT, which does not
+// guarantee the order of the returned rows, insert all rows one at a time.
+// Rows omitting all columns are inserted one at a time, too. Columns tagged
+// default or readonly are read back like with Insert.
+func InsertAll(db DB, tableName string, src interface{}, opts ...Option) error {
+	return InsertAllContext(context.Background(), withContext(db), tableName, src, opts...)
+}
+
+// InsertAllContext is like InsertAll, but uses the given context for the
+// statements.
+func InsertAllContext(ctx context.Context, db DBContext, tableName string, src interface{}, opts ...Option) error {
+	o := applyOptions(opts)
+
+	tables, strType, err := extractTables(src)
+	if err != nil {
+		return fmt.Errorf("sqlstruct.InsertAll: %v", err)
+	}
+
+	if len(tables) == 0 {
+		return nil
+	}
+
+	d := dialectOf(db)
+	typ := reflect.PtrTo(strType)
+
+	columns := make([][]*column, len(tables))
+	for i, table := range tables {
+		if err := generateIDs(ctx, strType, table); err != nil {
+			return err
+		}
+		columns[i] = insertColumns(table)
+	}
+
+	for start := 0; start < len(tables); {
+		size := maxBatchRows
+		if n := len(columns[start]); n > 0 && d.MaxParams()/n < size {
+			size = d.MaxParams() / n
+		}
+		if d.InsertID() == InsertIDLastInsertID && len(generatedPKs(tables[start])) > 0 {
+			size = 1
+		}
+		if d.InsertID() == InsertIDOutput || len(columns[start]) == 0 {
+			size = 1
+		}
+
+		// rows of a batch must agree on the inserted columns
+		end := start + 1
+		for end < len(tables) && end-start < size && sameColumns(columns[end], columns[start]) {
+			end++
+		}
+
+		if err := insertRows(ctx, db, tableName, typ, tables[start:end], o.returnAll); err != nil {
+			return err
+		}
+
+		start = end
+	}
+
+	return nil
+}
+
+// extractTables extracts the tables of all elements of the given slice of
+// structs or pointers to structs.
+func extractTables(src interface{}) ([]*Table, reflect.Type, error) {
+	sliceVal := reflect.ValueOf(src)
+	if sliceVal.Kind() == reflect.Ptr {
+		sliceVal = sliceVal.Elem()
+	}
+
+	if sliceVal.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("must be called with a slice; got %v", reflect.TypeOf(src))
+	}
+
+	strType := sliceVal.Type().Elem()
+	isPtr := strType.Kind() == reflect.Ptr
+	if isPtr {
+		strType = strType.Elem()
+	}
+
+	if strType.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("elements must be structs or pointers to structs; got %v", sliceVal.Type().Elem())
+	}
+
+	info, err := structInfoOf(strType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tables := make([]*Table, sliceVal.Len())
+	for i := range tables {
+		el := sliceVal.Index(i)
+		if isPtr {
+			if el.IsNil() {
+				return nil, nil, fmt.Errorf("element %d is nil", i)
+			}
+			el = el.Elem()
+		}
+		tables[i] = info.bind(el)
+	}
+
+	return tables, strType, nil
+}
@@ -0,0 +1,184 @@
+package sqlstruct
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+type batchUser struct {
+	ID      int
+	Name    string
+	Country string
+}
+
+// limitedDialect is Postgres with a small parameter limit.
+type limitedDialect struct {
+	Dialect
+}
+
+func (limitedDialect) MaxParams() int {
+	return 4
+}
+
+func returnedIDs(ids ...int64) fakeResult {
+	res := fakeResult{Columns: []string{"id"}}
+	for _, id := range ids {
+		res.Rows = append(res.Rows, []driver.Value{id})
+	}
+	return res
+}
+
+func TestInsertAll(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(returnedIDs(10, 11, 12))
+
+	users := []*batchUser{
+		{Name: "a", Country: "de"},
+		{Name: "b", Country: "at"},
+		{Name: "c", Country: "ch"},
+	}
+	if err := InsertAll(db, "user", users); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `INSERT INTO "user" ("name","country") VALUES ($1,$2),($3,$4),($5,$6) RETURNING "id"`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 6 || query.Args[4] != "c" {
+		t.Errorf("args=%v; wanted [a de b at c ch]", query.Args)
+	}
+
+	for i, user := range users {
+		if user.ID != 10+i {
+			t.Errorf("user.ID = %v; but want %v", user.ID, 10+i)
+		}
+	}
+}
+
+func TestInsertAllStructs(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(returnedIDs(1, 2))
+
+	users := []batchUser{{Name: "a"}, {Name: "b"}}
+	if err := InsertAll(db, "user", users); err != nil {
+		t.Fatal(err)
+	}
+
+	if users[0].ID != 1 || users[1].ID != 2 {
+		t.Errorf("users=%v; wanted IDs 1 and 2", users)
+	}
+}
+
+func TestInsertAllSplit(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	fake.push(returnedIDs(1, 2), returnedIDs(3, 4), returnedIDs(5))
+
+	users := make([]batchUser, 5)
+	if err := InsertAll(WithDialect(sqlDB, limitedDialect{Postgres}), "user", users); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 3 {
+		t.Fatalf("len(queries)=%v; wanted 3", len(fake.queries))
+	}
+
+	want := `INSERT INTO "user" ("name","country") VALUES ($1,$2) RETURNING "id"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	for i, user := range users {
+		if user.ID != i+1 {
+			t.Errorf("user.ID = %v; but want %v", user.ID, i+1)
+		}
+	}
+}
+
+func TestInsertAllMixedPKs(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(returnedIDs(7, 8), returnedIDs(1))
+
+	users := []batchUser{{ID: 7}, {ID: 8}, {}}
+	if err := InsertAll(db, "user", users); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INSERT INTO "user" ("id","name","country") VALUES ($1,$2,$3),($4,$5,$6) RETURNING "id"`
+	if q := fake.queries[0].Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if users[2].ID != 1 {
+		t.Errorf("user.ID = %v; but want 1", users[2].ID)
+	}
+}
+
+func TestInsertAllLastInsertID(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	fake.push(fakeResult{LastInsertID: 5}, fakeResult{LastInsertID: 6})
+
+	users := []batchUser{{Name: "a"}, {Name: "b"}}
+	if err := InsertAll(WithDialect(sqlDB, MySQL), "user", users); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 2 {
+		t.Errorf("len(queries)=%v; wanted 2", len(fake.queries))
+	}
+
+	if users[0].ID != 5 || users[1].ID != 6 {
+		t.Errorf("users=%v; wanted IDs 5 and 6", users)
+	}
+}
+
+func TestInsertAllReturnedCount(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(returnedIDs(1))
+
+	if err := InsertAll(db, "user", []batchUser{{}, {}}); err == nil {
+		t.Error("Expected error for missing returned rows")
+	}
+}
+
+func TestInsertAllEmpty(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	if err := InsertAll(db, "user", []batchUser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 0 {
+		t.Errorf("Expected no statement to be executed; got %v", fake.queries)
+	}
+
+	if err := InsertAll(db, "user", batchUser{}); err == nil {
+		t.Error("Expected error for non-slice")
+	}
+}
+
+func TestInsertAllOutput(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	fake.push(returnedIDs(10), returnedIDs(11))
+
+	users := []*batchUser{{Name: "a"}, {Name: "b"}}
+	if err := InsertAll(WithDialect(sqlDB, SQLServer), "user", users); err != nil {
+		t.Fatal(err)
+	}
+
+	// OUTPUT does not guarantee the order of the returned rows
+	if len(fake.queries) != 2 {
+		t.Fatalf("len(queries)=%v; wanted 2", len(fake.queries))
+	}
+
+	want := `INSERT INTO [user] ([name],[country]) OUTPUT INSERTED.[id] VALUES (@p1,@p2)`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if users[0].ID != 10 || users[1].ID != 11 {
+		t.Errorf("ids=%v,%v; wanted 10,11", users[0].ID, users[1].ID)
+	}
+}
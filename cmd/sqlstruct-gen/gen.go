@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const directive = "//sqlstruct:generate"
+
+// importPath is the import path of package sqlstruct.
+const importPath = "github.com/rkusa/sqlstruct"
+
+type generator struct {
+	pkgName string
+	structs map[string]*ast.StructType
+	order   []string // struct names in source order
+	marked  map[string]bool
+	imports map[*ast.StructType]map[string]string // package name -> import path, of the file declaring the struct
+}
+
+// mapping is the generated mapping of a struct type.
+type mapping struct {
+	typeName string
+	columns  []string
+	targets  []string
+	inits    []embeddedPtr // embedded struct pointers to initialize, in order
+}
+
+type embeddedPtr struct {
+	path     string
+	typeName string
+}
+
+// generate parses the package in dir and returns the source of the mappers
+// for the given types or, if none are given, all annotated structs. The
+// output file is ignored while parsing.
+func generate(dir string, types []string, output string) ([]byte, error) {
+	g, err := parse(dir, output)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(types) == 0 {
+		for _, name := range g.order {
+			if g.marked[name] {
+				types = append(types, name)
+			}
+		}
+		if len(types) == 0 {
+			return nil, fmt.Errorf("no structs annotated with %s found in %s", directive, dir)
+		}
+	}
+
+	var mappings []*mapping
+	for _, name := range types {
+		st, ok := g.structs[name]
+		if !ok {
+			return nil, fmt.Errorf("struct type %s not found", name)
+		}
+
+		m := &mapping{typeName: name}
+		if err := g.walk(m, st, "s", "", map[string]bool{name: true}); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		mappings = append(mappings, m)
+	}
+
+	return g.render(mappings)
+}
+
+func parse(dir, output string) (*generator, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		structs: map[string]*ast.StructType{},
+		marked:  map[string]bool{},
+		imports: map[*ast.StructType]map[string]string{},
+	}
+	fset := token.NewFileSet()
+
+	for _, path := range files {
+		base := filepath.Base(path)
+		if strings.HasSuffix(base, "_test.go") || base == output {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		if g.pkgName == "" {
+			g.pkgName = file.Name.Name
+		} else if g.pkgName != file.Name.Name {
+			return nil, fmt.Errorf("multiple packages in %s: %s and %s", dir, g.pkgName, file.Name.Name)
+		}
+
+		imports, err := importsOf(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+
+				name := ts.Name.Name
+				g.structs[name] = st
+				g.imports[st] = imports
+				g.order = append(g.order, name)
+
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				if hasDirective(doc) {
+					if ts.TypeParams != nil {
+						return nil, fmt.Errorf("%s: generic structs are not supported", name)
+					}
+					g.marked[name] = true
+				}
+			}
+		}
+	}
+
+	if g.pkgName == "" {
+		return nil, fmt.Errorf("no Go files found in %s", dir)
+	}
+
+	return g, nil
+}
+
+// importsOf returns the import paths of file by the names they are referred
+// to. Packages imported without a name are assumed to be named like the last
+// element of their path.
+func importsOf(file *ast.File) (map[string]string, error) {
+	imports := map[string]string{}
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports, nil
+}
+
+func hasDirective(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+// walk adds the columns of st to m, applying the same rules as sqlstruct's
+// reflection based extraction. path is the expression to access the struct
+// and prefix the prefix of its column names.
+func (g *generator) walk(m *mapping, st *ast.StructType, path, prefix string, seen map[string]bool) error {
+	for _, f := range st.Fields.List {
+		nameTag, err := nameTagOf(f)
+		if err != nil {
+			return err
+		}
+
+		typ := f.Type
+		ptr := false
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+			ptr = true
+		}
+
+		if len(f.Names) == 0 { // embedded struct
+			var typeName string
+			switch t := typ.(type) {
+			case *ast.Ident:
+				typeName = t.Name
+			case *ast.SelectorExpr:
+				if !ast.IsExported(t.Sel.Name) {
+					continue // ignore unexported fields
+				}
+				if x, ok := t.X.(*ast.Ident); ok && g.imports[st][x.Name] == importPath && t.Sel.Name == "Tracking" {
+					continue // has no columns
+				}
+				return fmt.Errorf("embedded struct %s.%s from another package is not supported", t.X, t.Sel.Name)
+			default:
+				return fmt.Errorf("unsupported embedded field %T", typ)
+			}
+
+			if !ast.IsExported(typeName) {
+				continue // ignore unexported fields
+			}
+
+			embedded, ok := g.structs[typeName]
+			if !ok {
+				return fmt.Errorf("embedded struct %s not found", typeName)
+			}
+			if seen[typeName] {
+				return fmt.Errorf("recursive embedded struct %s", typeName)
+			}
+
+			// prefix column names
+			embeddedPrefix := nameOf(typeName, nameTag)
+			if embeddedPrefix == "_" {
+				embeddedPrefix = ""
+			} else {
+				embeddedPrefix += "_"
+			}
+
+			embeddedPath := path + "." + typeName
+			if ptr {
+				m.inits = append(m.inits, embeddedPtr{embeddedPath, typeName})
+			}
+
+			seen[typeName] = true
+			err := g.walk(m, embedded, embeddedPath, prefix+embeddedPrefix, seen)
+			delete(seen, typeName)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if nameTag == "-" {
+			continue
+		}
+
+		for _, name := range f.Names {
+			if !ast.IsExported(name.Name) {
+				continue // ignore unexported fields
+			}
+
+			m.columns = append(m.columns, prefix+nameOf(name.Name, nameTag))
+			if ptr {
+				// pointer fields are scanned into the value they point to
+				m.targets = append(m.targets, path+"."+name.Name)
+			} else {
+				m.targets = append(m.targets, "&"+path+"."+name.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// nameTagOf returns the name part of the sql tag of the given field.
+func nameTagOf(f *ast.Field) (string, error) {
+	if f.Tag == nil {
+		return "", nil
+	}
+
+	tag, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Split(reflect.StructTag(tag).Get("sql"), ",")[0], nil
+}
+
+func nameOf(fieldName, nameTag string) string {
+	if nameTag == "" {
+		return strings.ToLower(fieldName)
+	}
+	return nameTag
+}
+
+func (g *generator) render(mappings []*mapping) ([]byte, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "// Code generated by sqlstruct-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", g.pkgName)
+	fmt.Fprintf(&buf, "import \"github.com/rkusa/sqlstruct\"\n\n")
+	fmt.Fprintf(&buf, "func init() {\n")
+
+	for _, m := range mappings {
+		columns := make([]string, len(m.columns))
+		for i, c := range m.columns {
+			columns[i] = strconv.Quote(c)
+		}
+
+		fmt.Fprintf(&buf, "sqlstruct.RegisterMapper((*%s)(nil), &sqlstruct.Mapper{\n", m.typeName)
+		fmt.Fprintf(&buf, "Columns: []string{%s},\n", strings.Join(columns, ", "))
+		fmt.Fprintf(&buf, "Targets: func(v interface{}) []interface{} {\n")
+		fmt.Fprintf(&buf, "s := v.(*%s)\n", m.typeName)
+		for _, init := range m.inits {
+			fmt.Fprintf(&buf, "if %s == nil {\n%s = new(%s)\n}\n", init.path, init.path, init.typeName)
+		}
+		fmt.Fprintf(&buf, "return []interface{}{%s}\n", strings.Join(m.targets, ", "))
+		fmt.Fprintf(&buf, "},\n")
+		fmt.Fprintf(&buf, "})\n")
+	}
+
+	fmt.Fprintf(&buf, "}\n")
+
+	return format.Source(buf.Bytes())
+}
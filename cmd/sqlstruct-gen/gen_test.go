@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGolden(t *testing.T) {
+	cases := []struct {
+		dir   string
+		types []string
+	}{
+		{"basic", nil},
+		{"types", []string{"Item", "Order"}},
+	}
+
+	for _, c := range cases {
+		dir := filepath.Join("testdata", c.dir)
+		got, err := generate(dir, c.types, "sqlstruct_gen.go")
+		if err != nil {
+			t.Fatalf("%s: %v", c.dir, err)
+		}
+
+		golden := filepath.Join(dir, "sqlstruct_gen.go.golden")
+		if *update {
+			if err := os.WriteFile(golden, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: generated code differs from %s:\n%s", c.dir, golden, got)
+		}
+	}
+}
+
+// TestExampleUpToDate makes sure the generated code of the example package,
+// whose parity with the reflection based mapping is tested there, is current.
+func TestExampleUpToDate(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+	got, err := generate(dir, nil, "sqlstruct_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "sqlstruct_gen.go")
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date; run go generate", path)
+	}
+}
+
+func TestGenerateAliasedImport(t *testing.T) {
+	dir := t.TempDir()
+	src := "package models\n\nimport db \"github.com/rkusa/sqlstruct\"\n\n//sqlstruct:generate\ntype User struct {\n\tdb.Tracking\n\tID int\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := generate(dir, nil, "sqlstruct_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(got, []byte("[]interface{}{&s.ID}")) {
+		t.Errorf("generated code does not only map the ID column:\n%s", got)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	cases := []struct {
+		src   string
+		types []string
+		err   string
+	}{
+		{"type User struct{ ID int }", nil, "no structs annotated"},
+		{"type User struct{ ID int }", []string{"Admin"}, "struct type Admin not found"},
+		{"import \"time\"\n//sqlstruct:generate\ntype User struct{ time.Time }", nil, "from another package"},
+		{"//sqlstruct:generate\ntype User struct{ Base }", nil, "embedded struct Base not found"},
+		{"import sqlstruct \"time\"\n//sqlstruct:generate\ntype User struct{ sqlstruct.Tracking }", nil, "from another package"},
+	}
+
+	for _, c := range cases {
+		dir := t.TempDir()
+		src := "package models\n\n" + c.src + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := generate(dir, c.types, "sqlstruct_gen.go")
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("generate(%q)=%v; wanted error containing %q", c.src, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Package example contains structs with generated mappers, used to verify
+// that generated mappers match sqlstruct's reflection based mapping.
+package example
+
+//go:generate go run github.com/rkusa/sqlstruct/cmd/sqlstruct-gen
+
+// Address is embedded into User.
+type Address struct {
+	Street string
+	City   string `sql:"town"`
+}
+
+// Geo is embedded into User using a pointer and without prefix.
+type Geo struct {
+	Lat float64
+	Lng float64
+}
+
+// Meta is embedded into Geo-less structs to test nested embedding.
+type Meta struct {
+	CreatedBy string
+	*Audit
+}
+
+// Audit is embedded into Meta using a pointer.
+type Audit struct {
+	Version int `sql:",readonly"`
+}
+
+//sqlstruct:generate
+type User struct {
+	ID       int `sql:"user_id,pk"`
+	Name     string
+	Email    string `sql:"mail"`
+	Ignored  string `sql:"-"`
+	internal string
+	Address
+	*Geo `sql:"_"`
+}
+
+//sqlstruct:generate
+type Post struct {
+	ID    int
+	Title string
+	Meta  `sql:"m"`
+}
@@ -0,0 +1,71 @@
+package example
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rkusa/sqlstruct"
+)
+
+// TestParity makes sure the generated mappers yield the same columns as the
+// reflection based mapping; ExtractTable fails if a registered mapper differs
+// from it.
+func TestParity(t *testing.T) {
+	cases := []struct {
+		v       interface{}
+		columns []string
+	}{
+		{&User{}, []string{"user_id", "name", "mail", "address_street", "address_town", "lat", "lng"}},
+		{&Post{}, []string{"id", "title", "m_createdby", "m_audit_version"}},
+	}
+
+	for _, c := range cases {
+		table, err := sqlstruct.ExtractTable(c.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if names := table.Names(true, true); !reflect.DeepEqual(names, c.columns) {
+			t.Errorf("Names=%v; wanted %v", names, c.columns)
+		}
+	}
+}
+
+func TestParityValues(t *testing.T) {
+	user := User{ID: 1, Name: "rkusa", Address: Address{City: "Dresden"}}
+
+	table, err := sqlstruct.ExtractTable(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.Geo == nil {
+		t.Fatal("Expected embedded pointer to be initialized")
+	}
+
+	values := table.Values(true, true)
+	if *values[0].(*int) != 1 || *values[1].(*string) != "rkusa" || *values[4].(*string) != "Dresden" {
+		t.Errorf("Values=%v; wanted values of user", values)
+	}
+
+	*values[5].(*float64) = 51.05
+	if user.Lat != 51.05 {
+		t.Errorf("user.Lat = %v; but want 51.05", user.Lat)
+	}
+
+	if len(table.PKs) != 1 || table.PKs[0].Name != "user_id" {
+		t.Errorf("Expected user_id to be the primary key")
+	}
+}
+
+func TestParityReadonly(t *testing.T) {
+	table, err := sqlstruct.ExtractTable(&Post{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := table.Names(false, false)
+	if !reflect.DeepEqual(names, []string{"title", "m_createdby"}) {
+		t.Errorf("Names=%v; wanted [title m_createdby]", names)
+	}
+}
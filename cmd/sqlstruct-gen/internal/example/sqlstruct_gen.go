@@ -0,0 +1,28 @@
+// Code generated by sqlstruct-gen. DO NOT EDIT.
+
+package example
+
+import "github.com/rkusa/sqlstruct"
+
+func init() {
+	sqlstruct.RegisterMapper((*User)(nil), &sqlstruct.Mapper{
+		Columns: []string{"user_id", "name", "mail", "address_street", "address_town", "lat", "lng"},
+		Targets: func(v interface{}) []interface{} {
+			s := v.(*User)
+			if s.Geo == nil {
+				s.Geo = new(Geo)
+			}
+			return []interface{}{&s.ID, &s.Name, &s.Email, &s.Address.Street, &s.Address.City, &s.Geo.Lat, &s.Geo.Lng}
+		},
+	})
+	sqlstruct.RegisterMapper((*Post)(nil), &sqlstruct.Mapper{
+		Columns: []string{"id", "title", "m_createdby", "m_audit_version"},
+		Targets: func(v interface{}) []interface{} {
+			s := v.(*Post)
+			if s.Meta.Audit == nil {
+				s.Meta.Audit = new(Audit)
+			}
+			return []interface{}{&s.ID, &s.Title, &s.Meta.CreatedBy, &s.Meta.Audit.Version}
+		},
+	})
+}
@@ -0,0 +1,62 @@
+// Command sqlstruct-gen generates reflection-free mappers for structs used with
+// the sqlstruct package.
+//
+// Usage:
+//
+//	sqlstruct-gen [-type User,Admin] [-output sqlstruct_gen.go] [dir]
+//
+// It reads the Go package in dir (defaults to the current directory) and
+// writes a file registering a sqlstruct.Mapper for each of the given types.
+// Without -type, mappers are generated for all structs annotated with a
+// //sqlstruct:generate comment:
+//
+//	//go:generate sqlstruct-gen
+//
+//	//sqlstruct:generate
+//	type User struct {
+//		ID   int
+//		Name string
+//	}
+//
+// The generated code follows the same tag rules as sqlstruct itself; mappers
+// that are out of date are detected at runtime.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of type names; defaults to all annotated structs")
+	output := flag.String("output", "sqlstruct_gen.go", "output file name, relative to dir")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: sqlstruct-gen [flags] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	var types []string
+	if *typeNames != "" {
+		types = strings.Split(*typeNames, ",")
+	}
+
+	src, err := generate(dir, types, *output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sqlstruct-gen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, *output), src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "sqlstruct-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
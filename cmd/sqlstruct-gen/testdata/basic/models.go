@@ -0,0 +1,24 @@
+package basic
+
+import "time"
+
+//sqlstruct:generate
+type User struct {
+	ID          int `sql:",pk"`
+	First, Last string
+	Created     time.Time `sql:"created_at,readonly"`
+	Nick        *string
+	Skip        bool `sql:"-"`
+	unexported  int
+	Address
+}
+
+type Address struct {
+	City string
+}
+
+// Admin is not annotated.
+type Admin struct {
+	User
+	Role string
+}
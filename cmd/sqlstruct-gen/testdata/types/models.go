@@ -0,0 +1,23 @@
+package types
+
+import "github.com/rkusa/sqlstruct"
+
+type Base struct {
+	ID int
+}
+
+type Extra struct {
+	Note string
+}
+
+type Item struct {
+	*Base `sql:"_"`
+	*Extra
+	Name string
+}
+
+type Order struct {
+	sqlstruct.Tracking
+	Base  `sql:"order"`
+	Total int
+}
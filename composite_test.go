@@ -0,0 +1,164 @@
+package sqlstruct
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestLoadCompositeKey(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"tenant_id", "id", "role"},
+		Rows:    [][]driver.Value{{int64(2), int64(7), "admin"}},
+	})
+
+	var m membership
+	if err := Load(db, "membership", &m, 2, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `SELECT "tenant_id","id","role" FROM "membership" WHERE "tenant_id"=$1 AND "id"=$2 LIMIT 1`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 2 || query.Args[0] != int64(2) || query.Args[1] != int64(7) {
+		t.Errorf("args=%v; wanted [2 7]", query.Args)
+	}
+
+	if m.Role != "admin" {
+		t.Errorf("m.Role=%v; wanted admin", m.Role)
+	}
+}
+
+func TestLoadCompositeKeyStruct(t *testing.T) {
+	type MembershipKey struct {
+		Tenant int `sql:"tenant_id"`
+		ID     int
+	}
+
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"tenant_id", "id", "role"},
+		Rows:    [][]driver.Value{{int64(2), int64(7), "admin"}},
+	}, fakeResult{
+		Columns: []string{"tenant_id", "id", "role"},
+		Rows:    [][]driver.Value{{int64(2), int64(7), "admin"}},
+	})
+
+	var m membership
+	if err := Load(db, "membership", &m, MembershipKey{Tenant: 2, ID: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	if args := fake.last(t).Args; len(args) != 2 || args[0] != int64(2) || args[1] != int64(7) {
+		t.Errorf("args=%v; wanted [2 7]", args)
+	}
+
+	// the key struct may also be the struct itself
+	if err := Load(db, "membership", &m, &membership{TenantID: 2, ID: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	if args := fake.last(t).Args; len(args) != 2 || args[0] != int64(2) || args[1] != int64(7) {
+		t.Errorf("args=%v; wanted [2 7]", args)
+	}
+}
+
+func TestLoadCompositeKeyInvalid(t *testing.T) {
+	db, _ := newFakeDB(t)
+
+	var m membership
+	if err := Load(db, "membership", &m, 2); err == nil {
+		t.Error("Expected error for missing key value")
+	}
+
+	if err := Load(db, "membership", &m, 2, 7, 9); err == nil {
+		t.Error("Expected error for too many key values")
+	}
+
+	if err := Load(db, "membership", &m, struct{ ID int }{7}); err == nil {
+		t.Error("Expected error for key struct missing a primary key")
+	}
+}
+
+type tenantDocument struct {
+	TenantID int    `sql:"tenant_id,pk"`
+	Folder   string `sql:",pk"`
+	Name     string `sql:",pk"`
+	Content  string
+}
+
+func TestCompositeKeyThreeColumns(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"tenant_id", "folder", "name", "content"},
+		Rows:    [][]driver.Value{{int64(1), "docs", "readme", "hello"}},
+	})
+
+	var doc tenantDocument
+	if err := Load(db, "document", &doc, 1, "docs", "readme"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `SELECT "tenant_id","folder","name","content" FROM "document" WHERE "tenant_id"=$1 AND "folder"=$2 AND "name"=$3 LIMIT 1`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if doc.Content != "hello" {
+		t.Errorf("doc.Content=%v; wanted hello", doc.Content)
+	}
+
+	doc.Content = "world"
+	if err := Update(db, "document", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want = `UPDATE "document" SET "content"=$1 WHERE "tenant_id"=$2 AND "folder"=$3 AND "name"=$4`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 4 || query.Args[0] != "world" || query.Args[3] != "readme" {
+		t.Errorf("args=%v; wanted [world 1 docs readme]", query.Args)
+	}
+
+	if err := Delete(db, "document", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	want = `DELETE FROM "document" WHERE "tenant_id"=$1 AND "folder"=$2 AND "name"=$3`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestReload(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"tenant_id", "folder", "name", "content"},
+		Rows:    [][]driver.Value{{int64(1), "docs", "readme", "hello"}},
+	})
+
+	doc := tenantDocument{TenantID: 1, Folder: "docs", Name: "readme"}
+	if err := Reload(db, "document", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `SELECT "tenant_id","folder","name","content" FROM "document" WHERE "tenant_id"=$1 AND "folder"=$2 AND "name"=$3 LIMIT 1`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 3 || query.Args[0] != int64(1) || query.Args[1] != "docs" || query.Args[2] != "readme" {
+		t.Errorf("args=%v; wanted [1 docs readme]", query.Args)
+	}
+
+	if doc.Content != "hello" {
+		t.Errorf("doc.Content=%v; wanted hello", doc.Content)
+	}
+}
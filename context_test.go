@@ -0,0 +1,110 @@
+package sqlstruct
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+type ctxUser struct {
+	ID   int
+	Name string
+}
+
+func TestContextCanceled(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	// make sure the connection is established before canceling
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	user := ctxUser{ID: 1, Name: "rkusa"}
+	var users []*ctxUser
+
+	calls := map[string]func() error{
+		"InsertContext": func() error { return InsertContext(ctx, db, "user", &ctxUser{Name: "rkusa"}) },
+		"UpdateContext": func() error { return UpdateContext(ctx, db, "user", &user) },
+		"DeleteContext": func() error { return DeleteContext(ctx, db, "user", &user) },
+		"LoadContext":   func() error { return LoadContext(ctx, db, "user", &user, 1) },
+		"QueryRowContext": func() error {
+			return QueryRowContext(ctx, db, &user, `SELECT * FROM "user"`)
+		},
+		"QueryAllContext": func() error {
+			return QueryAllContext(ctx, db, &users, `SELECT * FROM "user"`)
+		},
+	}
+
+	for name, call := range calls {
+		if err := call(); err != context.Canceled {
+			t.Errorf("%v: expected context.Canceled; got %v", name, err)
+		}
+	}
+
+	if len(fake.queries) != 0 {
+		t.Errorf("Expected no statement to be executed; got %v", fake.queries)
+	}
+}
+
+func TestContextConn(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "name"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa"}},
+	})
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	user := ctxUser{}
+	if err := LoadContext(ctx, WithDialectContext(conn, SQLite), "user", &user, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `SELECT "id","name" FROM "user" WHERE "id"=? LIMIT 1`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.Name != "rkusa" {
+		t.Errorf("user.Name = %v; but want rkusa", user.Name)
+	}
+}
+
+// plainDB only implements DB, without context support.
+type plainDB struct {
+	db *sql.DB
+}
+
+func (p plainDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return p.db.Exec(query, args...)
+}
+
+func (p plainDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return p.db.Query(query, args...)
+}
+
+func (p plainDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return p.db.QueryRow(query, args...)
+}
+
+func TestContextPlainDB(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	if err := Update(plainDB{db}, "user", &ctxUser{1, "rkusa"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `UPDATE "user" SET "name"=$1 WHERE "id"=$2`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
@@ -0,0 +1,81 @@
+package sqlstruct
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// Cursor iterates over the rows of a query, scanning them into structs one at
+// a time instead of materializing the whole result like QueryAll.
+//
+//	cur, err := sqlstruct.Query(db, `SELECT * FROM "user"`)
+//	if err != nil {
+//		return err
+//	}
+//	defer cur.Close()
+//
+//	for cur.Next() {
+//		var user User
+//		if err := cur.Scan(&user); err != nil {
+//			return err
+//		}
+//	}
+//	return cur.Err()
+type Cursor struct {
+	rows *sql.Rows
+	plan *scanPlan
+	typ  reflect.Type
+}
+
+// Query executes the given query and returns a cursor over its rows.
+func Query(db DB, query string, args ...interface{}) (*Cursor, error) {
+	return QueryContext(context.Background(), withContext(db), query, args...)
+}
+
+// QueryContext is like Query, but uses the given context for the query.
+func QueryContext(ctx context.Context, db DBContext, query string, args ...interface{}) (*Cursor, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cursor{rows: rows}, nil
+}
+
+// Next prepares the next row for Scan. It returns false if there are no more
+// rows or an error occurred, which is reported by Err.
+func (c *Cursor) Next() bool {
+	return c.rows.Next()
+}
+
+// Scan scans the current row into dst, which must be a pointer to a struct.
+// Columns not found in the struct are discarded.
+func (c *Cursor) Scan(dst interface{}) error {
+	t := reflect.TypeOf(dst)
+	if t.Kind() != reflect.Ptr {
+		return fmt.Errorf("sqlstruct.Cursor.Scan: must be called with a pointer; got %v", t)
+	}
+
+	if c.plan == nil || c.typ != t.Elem() {
+		plan, err := newScanPlan(c.rows, t.Elem())
+		if err != nil {
+			return err
+		}
+		c.plan = plan
+		c.typ = t.Elem()
+	}
+
+	return c.plan.scan(c.rows, reflect.ValueOf(dst).Elem())
+}
+
+// Err returns the error, if any, that was encountered during iteration.
+func (c *Cursor) Err() error {
+	return c.rows.Err()
+}
+
+// Close closes the cursor. It is safe to call Close multiple times.
+func (c *Cursor) Close() error {
+	return c.rows.Close()
+}
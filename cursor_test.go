@@ -0,0 +1,54 @@
+package sqlstruct
+
+import "testing"
+
+func TestCursor(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(queryUserRows(3))
+
+	cur, err := Query(db, `SELECT * FROM "user"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+
+	n := 0
+	for cur.Next() {
+		user := queryUser{}
+		if err := cur.Scan(&user); err != nil {
+			t.Fatal(err)
+		}
+
+		n++
+		if user.ID != n || user.City != "Dresden" {
+			t.Errorf("user=%v; wanted {%v rkusa {Dresden}}", user, n)
+		}
+	}
+
+	if err := cur.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 3 {
+		t.Errorf("Scanned %v rows; wanted 3", n)
+	}
+}
+
+func TestCursorScanNonPointer(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(queryUserRows(1))
+
+	cur, err := Query(db, `SELECT * FROM "user"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+
+	if !cur.Next() {
+		t.Fatal("Expected a row")
+	}
+
+	if err := cur.Scan(queryUser{}); err == nil {
+		t.Error("Expected error when scanning into non-pointer")
+	}
+}
@@ -0,0 +1,359 @@
+package sqlstruct
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+type defaultUser struct {
+	ID     int
+	Name   string
+	Status string `sql:",default"`
+	Rank   int    `sql:",default"`
+}
+
+func TestInsertDefault(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "status", "rank"},
+		Rows:    [][]driver.Value{{int64(1), "active", int64(5)}},
+	})
+
+	user := defaultUser{Name: "rkusa"}
+	if err := Insert(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `INSERT INTO "user" ("name") VALUES ($1) RETURNING "id","status","rank"`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if user.Status != "active" || user.Rank != 5 {
+		t.Errorf("user=%v; wanted defaults to be read back", user)
+	}
+}
+
+func TestInsertDefaultSet(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "status", "rank"},
+		Rows:    [][]driver.Value{{int64(1), "blocked", int64(5)}},
+	})
+
+	user := defaultUser{Name: "rkusa", Status: "blocked"}
+	if err := Insert(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `INSERT INTO "user" ("name","status") VALUES ($1,$2) RETURNING "id","status","rank"`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 2 || query.Args[1] != "blocked" {
+		t.Errorf("args=%v; wanted [rkusa blocked]", query.Args)
+	}
+}
+
+func TestInsertAllDefault(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "status", "rank"},
+		Rows: [][]driver.Value{
+			{int64(1), "active", int64(0)},
+			{int64(2), "active", int64(0)},
+		},
+	}, fakeResult{
+		Columns: []string{"id", "status", "rank"},
+		Rows:    [][]driver.Value{{int64(3), "blocked", int64(0)}},
+	})
+
+	users := []defaultUser{{Name: "a"}, {Name: "b"}, {Name: "c", Status: "blocked"}}
+	if err := InsertAll(db, "user", users); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 2 {
+		t.Fatalf("len(queries)=%v; wanted 2", len(fake.queries))
+	}
+
+	want := `INSERT INTO "user" ("name") VALUES ($1),($2) RETURNING "id","status","rank"`
+	if q := fake.queries[0].Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if users[1].Status != "active" || users[2].ID != 3 {
+		t.Errorf("users=%v; wanted defaults and keys to be read back", users)
+	}
+}
+
+type counter struct {
+	ID   int
+	Hits int `sql:",default"`
+}
+
+func TestInsertDefaultValues(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{Postgres, `INSERT INTO "counter" DEFAULT VALUES RETURNING "id","hits"`},
+		{MySQL, "INSERT INTO `counter` () VALUES ()"},
+		{SQLite, `INSERT INTO "counter" DEFAULT VALUES`},
+		{SQLServer, `INSERT INTO [counter] OUTPUT INSERTED.[id],INSERTED.[hits] DEFAULT VALUES`},
+	}
+
+	for _, test := range tests {
+		sqlDB, fake := newFakeDB(t)
+		for id := int64(1); id <= 2; id++ {
+			if test.dialect.InsertID() == InsertIDLastInsertID {
+				fake.push(fakeResult{LastInsertID: id})
+			} else {
+				fake.push(fakeResult{
+					Columns: []string{"id", "hits"},
+					Rows:    [][]driver.Value{{id, int64(0)}},
+				})
+			}
+		}
+
+		// rows without any columns are inserted one at a time
+		counters := []counter{{}, {}}
+		if err := InsertAll(WithDialect(sqlDB, test.dialect), "counter", counters); err != nil {
+			t.Fatalf("%T: %v", test.dialect, err)
+		}
+
+		if len(fake.queries) != 2 {
+			t.Fatalf("%T: len(queries)=%v; wanted 2", test.dialect, len(fake.queries))
+		}
+
+		if q := fake.queries[1].Query; q != test.want {
+			t.Errorf("%T: query=%v; wanted %v", test.dialect, q, test.want)
+		}
+
+		if counters[0].ID != 1 || counters[1].ID != 2 {
+			t.Errorf("%T: counters=%v; wanted keys to be read back", test.dialect, counters)
+		}
+	}
+}
+
+func TestInsertDefaultStmtCache(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	db := NewStmtCache(10).Wrap(sqlDB)
+
+	for _, user := range []defaultUser{{Name: "a"}, {Name: "b", Status: "blocked"}} {
+		fake.push(fakeResult{
+			Columns: []string{"id", "status", "rank"},
+			Rows:    [][]driver.Value{{int64(1), "active", int64(0)}},
+		})
+		if err := Insert(db, "user", &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fake.prepared != 2 {
+		t.Errorf("prepared=%v; wanted 2", fake.prepared)
+	}
+}
+
+func TestUpsertDefault(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "status", "rank"},
+		Rows:    [][]driver.Value{{int64(1), "active", int64(0)}},
+	})
+
+	user := defaultUser{ID: 1, Name: "rkusa", Rank: 2}
+	if err := Upsert(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INSERT INTO "user" ("id","name","rank") VALUES ($1,$2,$3)` +
+		` ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name","rank"=EXCLUDED."rank"` +
+		` RETURNING "id","status","rank"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestInsertValues(t *testing.T) {
+	user := defaultUser{Name: "a", Rank: 2}
+	columns, values, err := InsertValues(context.Background(), &user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(columns) != 2 || columns[0] != "name" || columns[1] != "rank" {
+		t.Errorf("columns=%v; wanted [name rank]", columns)
+	}
+
+	if len(values) != 2 || values[0] != &user.Name || values[1] != &user.Rank {
+		t.Errorf("values=%v; wanted pointers to Name and Rank", values)
+	}
+}
+
+// uuid mimics the common UUID types, e.g. github.com/google/uuid.
+type uuid [16]byte
+
+func (u *uuid) Scan(src interface{}) error {
+	copy(u[:], src.([]byte))
+	return nil
+}
+
+func (u uuid) Value() (driver.Value, error) {
+	return u[:], nil
+}
+
+type zeroerKey struct {
+	value string
+}
+
+func (k zeroerKey) IsZero() bool {
+	return k.value == "" || k.value == "none"
+}
+
+func TestIsZero(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		zero  bool
+	}{
+		{0, true},
+		{1, false},
+		{"", true},
+		{"a", false},
+		{uuid{}, true},
+		{uuid{1}, false},
+		{zeroerKey{"none"}, true},
+		{zeroerKey{"a"}, false},
+	}
+
+	for _, test := range tests {
+		if zero := isZero(reflect.ValueOf(test.value)); zero != test.zero {
+			t.Errorf("isZero(%v)=%v; wanted %v", test.value, zero, test.zero)
+		}
+	}
+}
+
+func TestInsertGeneratedUUID(t *testing.T) {
+	type Document struct {
+		ID   uuid `sql:",pk"`
+		Name string
+	}
+
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id"},
+		Rows:    [][]driver.Value{{[]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}}},
+	})
+
+	doc := Document{Name: "a"}
+	if err := Insert(db, "document", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INSERT INTO "document" ("name") VALUES ($1) RETURNING "id"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if doc.ID[15] != 16 {
+		t.Errorf("doc.ID=%v; wanted generated id", doc.ID)
+	}
+
+	doc = Document{ID: uuid{1}, Name: "a"}
+	fake.push(fakeResult{
+		Columns: []string{"id"},
+		Rows:    [][]driver.Value{{[]byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}}},
+	})
+	if err := Insert(db, "document", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	want = `INSERT INTO "document" ("id","name") VALUES ($1,$2) RETURNING "id"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestInsertGeneratedStringPK(t *testing.T) {
+	type Tag struct {
+		Slug string `sql:",pk"`
+		Name string
+	}
+
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"slug"},
+		Rows:    [][]driver.Value{{"go"}},
+	})
+
+	tag := Tag{Name: "Go"}
+	if err := Insert(db, "tag", &tag); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INSERT INTO "tag" ("name") VALUES ($1) RETURNING "slug"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if tag.Slug != "go" {
+		t.Errorf("tag.Slug=%v; wanted go", tag.Slug)
+	}
+}
+
+type membership struct {
+	TenantID int `sql:"tenant_id,pk"`
+	ID       int `sql:",pk"`
+	Role     string
+}
+
+func TestInsertMixedCompositeKey(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"tenant_id", "id"},
+		Rows:    [][]driver.Value{{int64(2), int64(7)}},
+	})
+
+	m := membership{TenantID: 2, Role: "admin"}
+	if err := Insert(db, "membership", &m); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `INSERT INTO "membership" ("tenant_id","role") VALUES ($1,$2) RETURNING "tenant_id","id"`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if m.TenantID != 2 || m.ID != 7 {
+		t.Errorf("membership=%v; wanted {2 7 admin}", m)
+	}
+}
+
+func TestInsertMixedCompositeKeyLastInsertID(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	fake.push(fakeResult{LastInsertID: 7})
+
+	m := membership{TenantID: 2, Role: "admin"}
+	if err := Insert(WithDialect(sqlDB, MySQL), "membership", &m); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "INSERT INTO `membership` (`tenant_id`,`role`) VALUES (?,?)"
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if m.ID != 7 {
+		t.Errorf("m.ID=%v; wanted 7", m.ID)
+	}
+
+	if err := Insert(WithDialect(sqlDB, MySQL), "membership", &membership{}); err == nil {
+		t.Error("Expected error for multiple generated primary keys")
+	}
+}
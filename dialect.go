@@ -0,0 +1,329 @@
+package sqlstruct
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// InsertIDStrategy describes how the primary keys generated by the database
+// are read back after an INSERT.
+type InsertIDStrategy int
+
+const (
+	// InsertIDReturning reads generated keys using INSERT ... RETURNING.
+	InsertIDReturning InsertIDStrategy = iota
+	// InsertIDLastInsertID reads the generated key using
+	// sql.Result.LastInsertId.
+	InsertIDLastInsertID
+	// InsertIDOutput reads generated keys using INSERT ... OUTPUT INSERTED.
+	InsertIDOutput
+)
+
+// UpsertStrategy describes how Upsert resolves conflicting rows.
+type UpsertStrategy int
+
+const (
+	// UpsertOnConflict uses INSERT ... ON CONFLICT (...) DO UPDATE SET.
+	UpsertOnConflict UpsertStrategy = iota
+	// UpsertOnDuplicateKey uses INSERT ... ON DUPLICATE KEY UPDATE.
+	UpsertOnDuplicateKey
+	// UpsertUnsupported indicates that Upsert is not supported.
+	UpsertUnsupported
+)
+
+// Dialect describes the SQL flavour of a database, i.e. everything that differs
+// between databases when building the statements used by Insert, Update, Delete
+// and Load. Dialects identify cached statements and must thus be comparable,
+// e.g. pointers or structs without slice, map or func fields.
+type Dialect interface {
+	// Quote quotes the given identifier, e.g. a table or column name, escaping
+	// embedded quote characters.
+	Quote(s string) string
+	// Placeholder returns the bind parameter of the n-th (1-based) argument.
+	Placeholder(n int) string
+	// Returning reports whether statements support a RETURNING clause.
+	Returning() bool
+	// InsertID returns the strategy used to read back generated primary keys.
+	InsertID() InsertIDStrategy
+	// Limit returns the clauses restricting a SELECT to n rows: top is placed
+	// right after SELECT and limit at the end of the statement. Unused
+	// clauses are empty.
+	Limit(n int) (top, limit string)
+	// MaxParams returns the maximum number of bind parameters per statement.
+	MaxParams() int
+	// CurrentTimestamp returns the expression evaluating to the current date
+	// and time, e.g. used by Delete to soft delete rows.
+	CurrentTimestamp() string
+	// Upsert returns the strategy used to resolve conflicts in Upsert.
+	Upsert() UpsertStrategy
+}
+
+var (
+	// Postgres is the dialect of PostgreSQL.
+	Postgres Dialect = postgres{}
+	// MySQL is the dialect of MySQL and MariaDB.
+	MySQL Dialect = mysql{}
+	// SQLite is the dialect of SQLite.
+	SQLite Dialect = sqlite{}
+	// SQLServer is the dialect of Microsoft SQL Server.
+	SQLServer Dialect = sqlserver{}
+)
+
+// DefaultDialect is the dialect used for databases that have not been wrapped
+// using WithDialect.
+var DefaultDialect = Postgres
+
+type postgres struct{}
+
+func (postgres) Quote(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+func (postgres) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgres) Returning() bool {
+	return true
+}
+
+func (postgres) InsertID() InsertIDStrategy {
+	return InsertIDReturning
+}
+
+func (postgres) Limit(n int) (string, string) {
+	return "", "LIMIT " + strconv.Itoa(n)
+}
+
+func (postgres) MaxParams() int {
+	return 65535
+}
+
+func (postgres) CurrentTimestamp() string {
+	return "CURRENT_TIMESTAMP"
+}
+
+func (postgres) Upsert() UpsertStrategy {
+	return UpsertOnConflict
+}
+
+type mysql struct{}
+
+func (mysql) Quote(s string) string {
+	return "`" + strings.Replace(s, "`", "``", -1) + "`"
+}
+
+func (mysql) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysql) Returning() bool {
+	return false
+}
+
+func (mysql) InsertID() InsertIDStrategy {
+	return InsertIDLastInsertID
+}
+
+func (mysql) Limit(n int) (string, string) {
+	return "", "LIMIT " + strconv.Itoa(n)
+}
+
+func (mysql) MaxParams() int {
+	return 65535
+}
+
+func (mysql) CurrentTimestamp() string {
+	// with microseconds, like the timestamps written by the driver
+	return "CURRENT_TIMESTAMP(6)"
+}
+
+func (mysql) Upsert() UpsertStrategy {
+	return UpsertOnDuplicateKey
+}
+
+type sqlite struct{}
+
+func (sqlite) Quote(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+func (sqlite) Placeholder(n int) string {
+	return "?"
+}
+
+func (sqlite) Returning() bool {
+	return false
+}
+
+func (sqlite) InsertID() InsertIDStrategy {
+	return InsertIDLastInsertID
+}
+
+func (sqlite) Limit(n int) (string, string) {
+	return "", "LIMIT " + strconv.Itoa(n)
+}
+
+func (sqlite) MaxParams() int {
+	return 32766
+}
+
+func (sqlite) CurrentTimestamp() string {
+	return "CURRENT_TIMESTAMP"
+}
+
+func (sqlite) Upsert() UpsertStrategy {
+	return UpsertOnConflict
+}
+
+type sqlserver struct{}
+
+func (sqlserver) Quote(s string) string {
+	return "[" + strings.Replace(s, "]", "]]", -1) + "]"
+}
+
+func (sqlserver) Placeholder(n int) string {
+	return "@p" + strconv.Itoa(n)
+}
+
+func (sqlserver) Returning() bool {
+	return false
+}
+
+func (sqlserver) InsertID() InsertIDStrategy {
+	return InsertIDOutput
+}
+
+func (sqlserver) Limit(n int) (string, string) {
+	return "TOP " + strconv.Itoa(n), ""
+}
+
+func (sqlserver) MaxParams() int {
+	return 2100
+}
+
+func (sqlserver) CurrentTimestamp() string {
+	return "SYSDATETIME()"
+}
+
+func (sqlserver) Upsert() UpsertStrategy {
+	return UpsertUnsupported
+}
+
+type dialectDB struct {
+	db      DBContext
+	dialect Dialect
+}
+
+func (db *dialectDB) Dialect() Dialect {
+	return db.dialect
+}
+
+func (db *dialectDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *dialectDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *dialectDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.db.QueryRowContext(context.Background(), query, args...)
+}
+
+func (db *dialectDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.db.ExecContext(ctx, query, args...)
+}
+
+func (db *dialectDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.db.QueryContext(ctx, query, args...)
+}
+
+func (db *dialectDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.db.QueryRowContext(ctx, query, args...)
+}
+
+// WithDialect returns a DB that uses the given dialect when building statements
+// for db. The returned DB also implements DBContext. WithDialect panics if the
+// dialect is not comparable.
+func WithDialect(db DB, dialect Dialect) DB {
+	checkDialect(dialect)
+	return &dialectDB{withContext(db), dialect}
+}
+
+// WithDialectContext is like WithDialect, but for databases only implementing
+// DBContext, e.g. *sql.Conn.
+func WithDialectContext(db DBContext, dialect Dialect) DBContext {
+	checkDialect(dialect)
+	return &dialectDB{db, dialect}
+}
+
+// checkDialect panics if d cannot be used as part of a map key.
+func checkDialect(d Dialect) {
+	defer func() {
+		if recover() != nil {
+			panic(fmt.Sprintf("sqlstruct: dialect of type %T is not comparable", d))
+		}
+	}()
+	_ = d == d
+}
+
+func dialectOf(db DBContext) Dialect {
+	if d, ok := db.(interface{ Dialect() Dialect }); ok {
+		return d.Dialect()
+	}
+	return DefaultDialect
+}
+
+func quoteTable(d Dialect, name string) (string, error) {
+	parts, err := SplitTableName(name)
+	if err != nil {
+		return "", err
+	}
+	for i, part := range parts {
+		parts[i] = d.Quote(part)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// SplitTableName splits the given, optionally schema qualified, table name into
+// its identifiers, e.g. "public.user" into "public" and "user". Names with
+// identifiers that cannot be quoted safely are rejected, like by Insert and
+// the other helpers.
+func SplitTableName(name string) ([]string, error) {
+	parts := strings.Split(name, ".")
+	for _, part := range parts {
+		if err := checkIdentifier(part); err != nil {
+			return nil, fmt.Errorf("sqlstruct: invalid table name %q", name)
+		}
+	}
+	return parts, nil
+}
+
+// checkIdentifier rejects identifiers that cannot be quoted safely.
+func checkIdentifier(s string) error {
+	if s == "" || !utf8.ValidString(s) || strings.IndexByte(s, 0) != -1 {
+		return fmt.Errorf("sqlstruct: invalid identifier %q", s)
+	}
+	return nil
+}
+
+func quoteAll(d Dialect, names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = d.Quote(name)
+	}
+	return quoted
+}
+
+func placeholders(d Dialect, offset, count int) []string {
+	placeholders := make([]string, count)
+	for i := 0; i < count; i++ {
+		placeholders[i] = d.Placeholder(offset + i)
+	}
+	return placeholders
+}
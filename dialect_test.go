@@ -0,0 +1,390 @@
+package sqlstruct
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestDialectQuote(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{Postgres, `"user"`},
+		{MySQL, "`user`"},
+		{SQLite, `"user"`},
+	}
+
+	for _, c := range cases {
+		if got := c.dialect.Quote("user"); got != c.want {
+			t.Errorf("Quote(user)=%v; wanted %v", got, c.want)
+		}
+	}
+}
+
+func TestDialectPlaceholder(t *testing.T) {
+	if p := Postgres.Placeholder(2); p != "$2" {
+		t.Errorf("Placeholder(2)=%v; wanted $2", p)
+	}
+
+	if p := MySQL.Placeholder(2); p != "?" {
+		t.Errorf("Placeholder(2)=%v; wanted ?", p)
+	}
+}
+
+func TestDialectUpdate(t *testing.T) {
+	type User struct {
+		ID   int
+		Name string
+	}
+
+	sqlDB, fake := newFakeDB(t)
+	db := WithDialect(sqlDB, MySQL)
+
+	if err := Update(db, "user", &User{1, "rkusa"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "UPDATE `user` SET `name`=? WHERE `id`=?"
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestDialectLoad(t *testing.T) {
+	type User struct {
+		ID   int
+		Name string
+	}
+
+	sqlDB, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "name"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa"}},
+	})
+
+	user := User{}
+	if err := Load(WithDialect(sqlDB, SQLite), "user", &user, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `SELECT "id","name" FROM "user" WHERE "id"=? LIMIT 1`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.Name != "rkusa" {
+		t.Errorf("user.Name = %v; but want rkusa", user.Name)
+	}
+}
+
+func TestDefaultDialect(t *testing.T) {
+	type User struct {
+		ID int
+	}
+
+	db, fake := newFakeDB(t)
+
+	if err := Delete(db, "user", &User{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `DELETE FROM "user" WHERE "id"=$1`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestInsertLastInsertID(t *testing.T) {
+	type User struct {
+		ID   int64
+		Name string
+	}
+
+	sqlDB, fake := newFakeDB(t)
+	fake.push(fakeResult{LastInsertID: 42, RowsAffected: 1})
+
+	user := User{Name: "rkusa"}
+	if err := Insert(WithDialect(sqlDB, MySQL), "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "INSERT INTO `user` (`name`) VALUES (?)"
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.ID != 42 {
+		t.Errorf("user.ID = %v; but want 42", user.ID)
+	}
+}
+
+func TestInsertLastInsertIDUnsigned(t *testing.T) {
+	type User struct {
+		ID uint
+	}
+
+	sqlDB, fake := newFakeDB(t)
+	fake.push(fakeResult{LastInsertID: 7, RowsAffected: 1})
+
+	user := User{}
+	if err := Insert(WithDialect(sqlDB, SQLite), "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != 7 {
+		t.Errorf("user.ID = %v; but want 7", user.ID)
+	}
+}
+
+func TestInsertLastInsertIDProvidedPK(t *testing.T) {
+	type User struct {
+		ID int
+	}
+
+	sqlDB, fake := newFakeDB(t)
+	fake.push(fakeResult{LastInsertID: 42, RowsAffected: 1})
+
+	user := User{ID: 3}
+	if err := Insert(WithDialect(sqlDB, MySQL), "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != 3 {
+		t.Errorf("user.ID = %v; but want 3", user.ID)
+	}
+}
+
+func TestInsertLastInsertIDCompositePK(t *testing.T) {
+	type Membership struct {
+		UserID  int `sql:",pk"`
+		GroupID int `sql:",pk"`
+	}
+
+	sqlDB, fake := newFakeDB(t)
+
+	err := Insert(WithDialect(sqlDB, MySQL), "membership", &Membership{})
+	if err == nil {
+		t.Fatal("Expected error for composite primary key")
+	}
+
+	if len(fake.queries) != 0 {
+		t.Errorf("Expected no statement to be executed; got %v", fake.queries)
+	}
+}
+
+func TestInsertLastInsertIDStringPK(t *testing.T) {
+	type User struct {
+		Email string `sql:",pk"`
+	}
+
+	sqlDB, fake := newFakeDB(t)
+
+	if err := Insert(WithDialect(sqlDB, MySQL), "user", &User{"a@b.c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "INSERT INTO `user` (`email`) VALUES (?)"
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestSQLServerInsert(t *testing.T) {
+	type User struct {
+		ID   int
+		Name string
+	}
+
+	sqlDB, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id"},
+		Rows:    [][]driver.Value{{int64(5)}},
+	})
+
+	user := User{Name: "rkusa"}
+	if err := Insert(WithDialect(sqlDB, SQLServer), "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := "INSERT INTO [user] ([name]) OUTPUT INSERTED.[id] VALUES (@p1)"
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 1 || query.Args[0] != "rkusa" {
+		t.Errorf("args=%v; wanted [rkusa]", query.Args)
+	}
+
+	if user.ID != 5 {
+		t.Errorf("user.ID = %v; but want 5", user.ID)
+	}
+}
+
+func TestSQLServerLoad(t *testing.T) {
+	type User struct {
+		ID   int
+		Name string
+	}
+
+	sqlDB, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "name"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa"}},
+	})
+
+	user := User{}
+	if err := Load(WithDialect(sqlDB, SQLServer), "user", &user, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "SELECT TOP 1 [id],[name] FROM [user] WHERE [id]=@p1"
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.ID != 1 || user.Name != "rkusa" {
+		t.Errorf("user=%v; wanted {1 rkusa}", user)
+	}
+}
+
+func TestSQLServerUpdate(t *testing.T) {
+	type User struct {
+		ID      int
+		Name    string
+		Country string
+	}
+
+	sqlDB, fake := newFakeDB(t)
+
+	if err := Update(WithDialect(sqlDB, SQLServer), "user", &User{1, "rkusa", "Germany"}); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := "UPDATE [user] SET [name]=@p1,[country]=@p2 WHERE [id]=@p3"
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 3 || query.Args[2] != int64(1) {
+		t.Errorf("args=%v; wanted [rkusa Germany 1]", query.Args)
+	}
+}
+
+func TestDialectQuoteEscaping(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		in      string
+		want    string
+	}{
+		{Postgres, `my"table`, `"my""table"`},
+		{MySQL, "my`table", "`my``table`"},
+		{SQLite, `my"table`, `"my""table"`},
+		{SQLServer, "my]table", "[my]]table]"},
+	}
+
+	for _, c := range cases {
+		if got := c.dialect.Quote(c.in); got != c.want {
+			t.Errorf("Quote(%v)=%v; wanted %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestQuoteTable(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		in      string
+		want    string
+	}{
+		{Postgres, "user", `"user"`},
+		{Postgres, "audit.events", `"audit"."events"`},
+		{MySQL, "audit.events", "`audit`.`events`"},
+		{SQLServer, "dbo.events", "[dbo].[events]"},
+	}
+
+	for _, c := range cases {
+		got, err := quoteTable(c.dialect, c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("quoteTable(%v)=%v; wanted %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestQuoteTableInvalid(t *testing.T) {
+	for _, name := range []string{"", "audit.", ".events", "a..b", "us\x00er", "\xff"} {
+		if _, err := quoteTable(Postgres, name); err == nil {
+			t.Errorf("quoteTable(%q) should fail", name)
+		}
+	}
+}
+
+func TestSchemaQualifiedTable(t *testing.T) {
+	type Event struct {
+		ID int
+	}
+
+	db, fake := newFakeDB(t)
+
+	if err := Delete(db, "audit.events", &Event{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `DELETE FROM "audit"."events" WHERE "id"=$1`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestInvalidTableName(t *testing.T) {
+	type Event struct {
+		ID int
+	}
+
+	db, fake := newFakeDB(t)
+
+	if err := Delete(db, "audit..events", &Event{1}); err == nil {
+		t.Fatal("Expected error for invalid table name")
+	}
+
+	if len(fake.queries) != 0 {
+		t.Errorf("Expected no statement to be executed; got %v", fake.queries)
+	}
+}
+
+func TestSplitTableName(t *testing.T) {
+	parts, err := SplitTableName("audit.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parts) != 2 || parts[0] != "audit" || parts[1] != "events" {
+		t.Errorf("parts=%v; wanted [audit events]", parts)
+	}
+
+	for _, name := range []string{"", "audit..events", "user\x00"} {
+		if _, err := SplitTableName(name); err == nil {
+			t.Errorf("Expected error for table name %q", name)
+		}
+	}
+}
+
+type sliceDialect struct {
+	Dialect
+	reserved []string
+}
+
+func TestWithDialectNotComparable(t *testing.T) {
+	sqlDB, _ := newFakeDB(t)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic")
+		}
+	}()
+	WithDialect(sqlDB, sliceDialect{Dialect: Postgres})
+}
@@ -0,0 +1,68 @@
+//go:build go1.18
+
+package sqlstruct
+
+import "context"
+
+// Get loads the row with the given primary key values from the table into a
+// new T. See Load.
+func Get[T any](db DB, tableName string, keys ...interface{}) (*T, error) {
+	return GetContext[T](context.Background(), withContext(db), tableName, keys...)
+}
+
+// GetContext is like Get, but uses the given context for the query.
+func GetContext[T any](ctx context.Context, db DBContext, tableName string, keys ...interface{}) (*T, error) {
+	dst := new(T)
+	if err := LoadContext(ctx, db, tableName, dst, keys...); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// Select executes the given query and scans all rows into a slice of T. Use a
+// pointer type for T to get a slice of pointers. See QueryAll.
+func Select[T any](db DB, query string, args ...interface{}) ([]T, error) {
+	return SelectContext[T](context.Background(), withContext(db), query, args...)
+}
+
+// SelectContext is like Select, but uses the given context for the query.
+func SelectContext[T any](ctx context.Context, db DBContext, query string, args ...interface{}) ([]T, error) {
+	var dst []T
+	if err := QueryAllContext(ctx, db, &dst, query, args...); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// InsertT is the type-safe version of Insert.
+func InsertT[T any](db DB, tableName string, src *T, opts ...Option) error {
+	return InsertContext(context.Background(), withContext(db), tableName, src, opts...)
+}
+
+// InsertTContext is like InsertT, but uses the given context for the
+// statement.
+func InsertTContext[T any](ctx context.Context, db DBContext, tableName string, src *T, opts ...Option) error {
+	return InsertContext(ctx, db, tableName, src, opts...)
+}
+
+// UpdateT is the type-safe version of Update.
+func UpdateT[T any](db DB, tableName string, src *T, opts ...Option) error {
+	return UpdateContext(context.Background(), withContext(db), tableName, src, opts...)
+}
+
+// UpdateTContext is like UpdateT, but uses the given context for the
+// statement.
+func UpdateTContext[T any](ctx context.Context, db DBContext, tableName string, src *T, opts ...Option) error {
+	return UpdateContext(ctx, db, tableName, src, opts...)
+}
+
+// DeleteT is the type-safe version of Delete.
+func DeleteT[T any](db DB, tableName string, src *T, opts ...Option) error {
+	return DeleteContext(context.Background(), withContext(db), tableName, src, opts...)
+}
+
+// DeleteTContext is like DeleteT, but uses the given context for the
+// statement.
+func DeleteTContext[T any](ctx context.Context, db DBContext, tableName string, src *T, opts ...Option) error {
+	return DeleteContext(ctx, db, tableName, src, opts...)
+}
@@ -0,0 +1,88 @@
+//go:build go1.18
+
+package sqlstruct
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "name"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa"}},
+	})
+
+	user, err := Get[stmtUser](db, "user", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != 1 || user.Name != "rkusa" {
+		t.Errorf("user=%v; wanted {1 rkusa}", user)
+	}
+
+	fake.push(fakeResult{Columns: []string{"id", "name"}})
+	if _, err := Get[stmtUser](db, "user", 2); err != sql.ErrNoRows {
+		t.Errorf("Expected no rows error; got %v", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(queryUserRows(2))
+
+	users, err := Select[queryUser](db, `SELECT * FROM "user"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("len(users)=%v; wanted 2", len(users))
+	}
+
+	if users[1].ID != 2 || users[1].City != "Dresden" {
+		t.Errorf("user=%v; wanted {2 rkusa {Dresden}}", users[1])
+	}
+}
+
+func TestSelectPtr(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(queryUserRows(2))
+
+	users, err := Select[*queryUser](db, `SELECT * FROM "user"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 2 || users[0].ID != 1 {
+		t.Errorf("users=%v; wanted 2 users", users)
+	}
+}
+
+func TestSelectNonStruct(t *testing.T) {
+	db, _ := newFakeDB(t)
+
+	if _, err := Select[int](db, `SELECT 1`); err == nil {
+		t.Error("Expected error for non-struct element type")
+	}
+}
+
+func TestInsertT(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id"},
+		Rows:    [][]driver.Value{{int64(3)}},
+	})
+
+	user := stmtUser{Name: "rkusa"}
+	if err := InsertT(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != 3 {
+		t.Errorf("user.ID = %v; but want 3", user.ID)
+	}
+}
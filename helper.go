@@ -1,7 +1,9 @@
 package sqlstruct
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -15,133 +17,605 @@ type DB interface {
 	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
-func Insert(db DB, tableName string, src interface{}) error {
+// DBContext is a generic database interface supporting contexts, matching
+// *sql.DB, *sql.Tx and *sql.Conn
+type DBContext interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// contextDB adapts a DB without context support to DBContext by ignoring the
+// context.
+type contextDB struct {
+	DB
+}
+
+func (db contextDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.Exec(query, args...)
+}
+
+func (db contextDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.Query(query, args...)
+}
+
+func (db contextDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.QueryRow(query, args...)
+}
+
+func withContext(db DB) DBContext {
+	if ctxDB, ok := db.(DBContext); ok {
+		return ctxDB
+	}
+	return contextDB{db}
+}
+
+var (
+	// ErrStaleObject is returned by Update and Delete if the row was changed
+	// since it was loaded, i.e. its column tagged version does not match
+	// anymore.
+	ErrStaleObject = errors.New("sqlstruct: stale object")
+	// ErrNotFound is returned by Update and Delete if no row matched the
+	// primary keys and MustExist is given.
+	ErrNotFound = errors.New("sqlstruct: not found")
+)
+
+// Insert inserts src. Zero primary keys are filled using their IDGenerator, if
+// any. Remaining zero primary keys and zero columns tagged default are
+// omitted, leaving their values to the database. Generated primary keys and
+// columns tagged default or readonly are read back into src; use ReturnAll to
+// read back all columns. Dialects reading back keys using LastInsertId only
+// read back the generated primary key, unless ReturnAll is given, which
+// reloads the row using a separate query.
+func Insert(db DB, tableName string, src interface{}, opts ...Option) error {
+	return InsertContext(context.Background(), withContext(db), tableName, src, opts...)
+}
+
+// InsertContext is like Insert, but uses the given context for the statement.
+func InsertContext(ctx context.Context, db DBContext, tableName string, src interface{}, opts ...Option) error {
+	o := applyOptions(opts)
+
 	table, err := ExtractTable(src)
 	if err != nil {
 		return err
 	}
 
-	includePK := true
+	if err := generateIDs(ctx, reflect.TypeOf(src), table); err != nil {
+		return err
+	}
 
-	if len(table.PKs) > 0 {
-		// TODO: allow including some of the pks?
-		for _, pk := range table.PKs {
-			switch pk.Type.Kind() {
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				if pk.Value.Int() == 0 {
-					includePK = false
-				}
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				if pk.Value.Uint() == 0 {
-					includePK = false
-				}
+	return insertRows(ctx, db, tableName, reflect.TypeOf(src), []*Table{table}, o.returnAll)
+}
+
+// generatedPKs returns the primary keys of table that are not provided, i.e.
+// are zero and thus generated by the database.
+func generatedPKs(table *Table) []*column {
+	var generated []*column
+	for _, pk := range table.PKs {
+		if isZero(pk.Value) {
+			generated = append(generated, pk)
+		}
+	}
+	return generated
+}
+
+// insertColumns returns the columns inserted for the current values of table.
+// Zero primary keys and zero columns tagged default are omitted; their values
+// are assigned by the database.
+func insertColumns(table *Table) []*column {
+	generated := generatedPKs(table)
+
+	columns := []*column{}
+outer:
+	for _, col := range table.ColumnsFiltered(true, false) {
+		for _, pk := range generated {
+			if pk == col {
+				continue outer
 			}
 		}
+		if col.Tags[defaultTag] && isZero(col.Value) {
+			continue
+		}
+		columns = append(columns, col)
 	}
+	return columns
+}
 
-	names := table.QuotedNames(includePK, false)
-	query := fmt.Sprintf(
-		"INSERT INTO %s (%s) VALUES (%s)",
-		Quote(tableName),
-		strings.Join(names, ","),
-		strings.Join(Placeholders(len(names)), ","),
-	)
+// InsertValues returns the columns Insert would insert for src, a pointer to a
+// struct, together with pointers to their values, after filling zero primary
+// keys using their IDGenerator. It allows inserting rows by other means, e.g.
+// using the COPY protocol of PostgreSQL (see package pqcopy).
+func InsertValues(ctx context.Context, src interface{}) ([]string, []interface{}, error) {
+	table, err := ExtractTable(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := generateIDs(ctx, reflect.TypeOf(src), table); err != nil {
+		return nil, nil, err
+	}
+
+	columns := insertColumns(table)
+	return columnNames(columns), columnAddrs(columns), nil
+}
+
+// lastInsertIDColumn returns the generated primary key of table to be read
+// back using LastInsertId, or nil if all primary keys are provided.
+func lastInsertIDColumn(table *Table) (*column, error) {
+	generated := generatedPKs(table)
+	if len(generated) == 0 {
+		return nil, nil
+	}
 
-	values := table.Values(includePK, false)
+	if len(generated) > 1 {
+		return nil, fmt.Errorf("cannot read back multiple generated primary keys using LastInsertId")
+	}
+
+	pk := generated[0]
+	if !isInt(pk.Type) && !isUint(pk.Type) {
+		return nil, fmt.Errorf("primary key field must be an integer to be read back using LastInsertId; got %v", pk.Type)
+	}
+
+	return pk, nil
+}
+
+// setLastInsertID sets the primary key pk to the id generated for res.
+func setLastInsertID(pk *column, res sql.Result) error {
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if isInt(pk.Type) {
+		pk.Value.SetInt(id)
+	} else {
+		pk.Value.SetUint(uint64(id))
+	}
+	return nil
+}
+
+func columnAddrs(columns []*column) []interface{} {
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		values[i] = col.Value.Addr().Interface()
+	}
+	return values
+}
+
+// sameColumns reports whether a and b consist of the same columns, in the same
+// order.
+func sameColumns(a, b []*column) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+// zeroer is implemented by types defining their own zero value, e.g.
+// time.Time.
+type zeroer interface {
+	IsZero() bool
+}
+
+// isZero reports whether v is the zero value of its type, using its IsZero
+// method if defined.
+func isZero(v reflect.Value) bool {
+	if v.CanInterface() {
+		if z, ok := v.Interface().(zeroer); ok {
+			return z.IsZero()
+		}
+		if v.CanAddr() {
+			if z, ok := v.Addr().Interface().(zeroer); ok {
+				return z.IsZero()
+			}
+		}
+	}
+	return v.IsZero()
+}
+
+// insertRows inserts the given tables of the struct type typ using a single
+// statement and reads back the generated primary keys and the columns tagged
+// default or readonly, or all columns. All tables must insert the same columns.
+func insertRows(ctx context.Context, db DBContext, tableName string, typ reflect.Type, tables []*Table, all bool) error {
+	d := dialectOf(db)
+	table := tables[0]
+	columns := insertColumns(table)
+	returned := returnedColumns(table, all, defaultTag, readonlyTag)
+
+	var generated *column
+	if d.InsertID() == InsertIDLastInsertID {
+		var err error
+		if generated, err = lastInsertIDColumn(table); err != nil {
+			return fmt.Errorf("sqlstruct.Insert: %v", err)
+		}
+
+		if generated != nil && len(tables) > 1 {
+			return fmt.Errorf("sqlstruct.Insert: cannot read back the primary keys of multiple rows using LastInsertId")
+		}
+	}
+
+	var values []interface{}
+	for _, table := range tables {
+		values = append(values, columnAddrs(insertColumns(table))...)
+	}
+
+	op := "insert:" + strings.Join(columnNames(columns), ",")
+	if len(tables) > 1 {
+		op += "-" + strconv.Itoa(len(tables))
+	}
+	if all {
+		op += "-all"
+	}
+	k := sqlKey{d, typ, tableName, op}
+	stmt, err := prepare(ctx, db, k, func() (string, error) {
+		return insertSQL(d, tableName, columns, len(tables), returned)
+	})
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
 
 	if len(table.PKs) == 0 {
-		if _, err := db.Exec(query, values...); err != nil {
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
 			return err
 		}
-	} else {
-		query += " RETURNING"
-		var returns []interface{}
+		return nil
+	}
+
+	switch d.InsertID() {
+	case InsertIDReturning, InsertIDOutput:
+		if len(tables) == 1 {
+			err := stmt.QueryRowContext(ctx, values...).Scan(columnAddrs(returned)...)
+			if err != nil {
+				return err
+			}
+			return nil
+		}
 
-		for i, pk := range table.PKs {
-			if i > 0 {
-				query += ","
+		rows, err := stmt.QueryContext(ctx, values...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		// rows are assumed to be returned in the order they were inserted,
+		// which PostgreSQL does for INSERT ... VALUES ... RETURNING; InsertAll
+		// does not batch rows for dialects using OUTPUT, which does not
+		// guarantee any order
+		n := 0
+		for rows.Next() {
+			if n >= len(tables) {
+				return fmt.Errorf("sqlstruct.InsertAll: more rows returned than inserted")
+			}
+			if err := rows.Scan(columnAddrs(returnedColumns(tables[n], all, defaultTag, readonlyTag))...); err != nil {
+				return err
 			}
-			query += Quote(pk.Name)
-			returns = append(returns, pk.Value.Addr().Interface())
+			n++
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
 		}
 
-		err := db.QueryRow(query, values...).Scan(returns...)
+		if n != len(tables) {
+			return fmt.Errorf("sqlstruct.InsertAll: %d rows inserted, but %d returned", len(tables), n)
+		}
+	case InsertIDLastInsertID:
+		res, err := stmt.ExecContext(ctx, values...)
 		if err != nil {
 			return err
 		}
+
+		if generated != nil {
+			if err := setLastInsertID(generated, res); err != nil {
+				return err
+			}
+		}
+
+		if all && len(returned) > len(table.PKs) {
+			for _, table := range tables {
+				if err := reload(ctx, db, tableName, typ, table); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("sqlstruct.Insert: unsupported insert id strategy %v", d.InsertID())
+	}
+
+	return nil
+}
+
+// returnedColumns returns the columns read back after writing a row of table:
+// the primary keys followed by the columns having any of the given tags, or
+// all columns.
+func returnedColumns(table *Table, all bool, tags ...string) []*column {
+	if all {
+		return table.ColumnsFiltered(true, true)
+	}
+
+	columns := append([]*column{}, table.PKs...)
+outer:
+	for _, col := range table.ColumnsFiltered(false, true) {
+		for _, tag := range tags {
+			if col.Tags[tag] {
+				columns = append(columns, col)
+				continue outer
+			}
+		}
 	}
+	return columns
+}
 
-	// MySQL/SQLite InsertID
-	//
-	// res, err := db.Exec(query, values...)
-	// if err != nil {
-	// 	return err
-	// }
+// reload loads all columns of the row of table by its primary keys.
+func reload(ctx context.Context, db DBContext, tableName string, typ reflect.Type, table *Table) error {
+	var pks []interface{}
+	for _, pk := range table.PKs {
+		pks = append(pks, pk.Value.Interface())
+	}
 
-	// pk, err := res.LastInsertId()
-	// if err != nil {
-	// 	return err
-	// }
+	d := dialectOf(db)
+	k := sqlKey{d, typ, tableName, "reload"}
+	stmt, err := prepare(ctx, db, k, func() (string, error) {
+		return selectSQL(d, tableName, table, table.PKs)
+	})
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.QueryRowContext(ctx, pks...).Scan(table.Values(true, true)...)
+}
 
-	// switch table.PK.Type.Kind() {
-	// case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-	// 	table.PK.Value.SetInt(pk)
-	// case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-	// 	table.PK.Value.SetUint(uint64(pk))
-	// default:
-	// 	return fmt.Errorf("sqlstruct: primary key field must be an integer")
-	// }
+// Update updates the row of src identified by its primary keys. Readonly
+// columns are read back into src; use ReturnAll to read back all columns.
+// Dialects without RETURNING or OUTPUT read back nothing, unless ReturnAll is
+// given, which reloads the row using a separate query. If src embeds Tracking,
+// only the columns changed since it was loaded are updated.
+//
+// If src has an integer field tagged version, e.g. `sql:",version"`, the row is
+// only updated if its version still matches the one of src, and the version
+// is incremented. ErrStaleObject is returned otherwise.
+func Update(db DB, tableName string, src interface{}, opts ...Option) error {
+	return UpdateContext(context.Background(), withContext(db), tableName, src, opts...)
+}
+
+// UpdateContext is like Update, but uses the given context for the statement.
+func UpdateContext(ctx context.Context, db DBContext, tableName string, src interface{}, opts ...Option) error {
+	o := applyOptions(opts)
+
+	table, err := ExtractTable(src)
+	if err != nil {
+		return err
+	}
+
+	set := updatableColumns(table)
+	op := "update"
+
+	tracking := trackingOf(src)
+	if tracking.tracked() {
+		if set = tracking.changed(set); len(set) == 0 {
+			if o.rowsAffected != nil {
+				*o.rowsAffected = 0
+			}
+			return nil
+		}
+		op = "update:" + strings.Join(columnNames(set), ",")
+	}
+
+	if o.returnAll {
+		op += "-all"
+	}
 
+	if err := updateRow(ctx, db, tableName, reflect.TypeOf(src), table, set, op, o); err != nil {
+		return err
+	}
+
+	tracking.snapshot(table.Columns)
 	return nil
 }
 
-func Update(db DB, tableName string, src interface{}) error {
+// UpdateColumns is like Update, but only updates the given columns, identified
+// by column or field name. Primary keys and readonly columns cannot be updated.
+// The options are the ones of Update, e.g. RowsAffected, MustExist or
+// ReturnAll.
+func UpdateColumns(db DB, tableName string, src interface{}, cols []string, opts ...Option) error {
+	return UpdateColumnsContext(context.Background(), withContext(db), tableName, src, cols, opts...)
+}
+
+// UpdateColumnsContext is like UpdateColumns, but uses the given context for
+// the statement.
+func UpdateColumnsContext(ctx context.Context, db DBContext, tableName string, src interface{}, cols []string, opts ...Option) error {
+	o := applyOptions(opts)
+
 	table, err := ExtractTable(src)
 	if err != nil {
 		return err
 	}
 
+	if len(cols) == 0 {
+		return fmt.Errorf("sqlstruct.UpdateColumns: no columns given")
+	}
+
+	updatable := updatableColumns(table)
+	var set []*column
+	for _, name := range cols {
+		col := findColumn(updatable, name)
+		if col == nil {
+			return fmt.Errorf("sqlstruct.UpdateColumns: unknown or non-updatable column %q", name)
+		}
+		if findColumn(set, col.Name) == nil {
+			set = append(set, col)
+		}
+	}
+
+	op := "update:" + strings.Join(columnNames(set), ",")
+	if o.returnAll {
+		op += "-all"
+	}
+
+	if err := updateRow(ctx, db, tableName, reflect.TypeOf(src), table, set, op, o); err != nil {
+		return err
+	}
+
+	// the incremented version was written, too
+	if version, _ := versionColumn(table); version != nil {
+		set = append(set, version)
+	}
+
+	trackingOf(src).remember(set)
+	return nil
+}
+
+// updatableColumns returns the columns of table written by Update: all but the
+// primary keys, readonly columns and the column tagged softdelete, which is only
+// changed by Delete and Restore.
+func updatableColumns(table *Table) []*column {
+	var columns []*column
+	for _, col := range table.ColumnsFiltered(false, false) {
+		if !col.Tags[softDeleteTag] {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+// updateRow sets the given columns of the row of table, which was extracted
+// from a struct of type typ. op identifies the statement in the cache.
+func updateRow(ctx context.Context, db DBContext, tableName string, typ reflect.Type, table *Table, set []*column, op string, o *options) error {
 	if len(table.PKs) == 0 {
 		return fmt.Errorf("sqlstruct.Update: primary key column required")
 	}
 
-	columns := table.QuotedNames(false, false)
-	placeholders := Placeholders(len(columns))
+	version, err := versionColumn(table)
+	if err != nil {
+		return fmt.Errorf("sqlstruct.Update: %v", err)
+	}
 
-	pairs := make([]string, len(columns))
-	for i, _ := range columns {
-		pairs[i] = fmt.Sprintf("%s=%s", columns[i], placeholders[i])
+	softDelete, err := softDeleteColumn(table)
+	if err != nil {
+		return fmt.Errorf("sqlstruct.Update: %v", err)
 	}
 
-	sql := "UPDATE %s SET %s WHERE"
-	args := []interface{}{Quote(tableName), strings.Join(pairs, ",")}
 	var pks []interface{}
+	for _, pk := range table.PKs {
+		pks = append(pks, pk.Value.Interface())
+	}
 
-	for i, pk := range table.PKs {
-		if i > 0 {
-			sql += "AND "
+	// the version is set to its successor, if the stored one is still current
+	var current, next interface{}
+	if version != nil {
+		var rest []*column
+		for _, col := range set {
+			if col != version {
+				rest = append(rest, col)
+			}
 		}
-		sql += " %s=%s"
-		args = append(args, Quote(pk.Name))
-		args = append(args, Placeholder(len(columns)+1+i))
-		pks = append(pks, pk.Value.Interface())
+		set = append(rest, version)
+		current, next = version.Value.Interface(), nextVersion(version.Value)
 	}
 
-	query := fmt.Sprintf(
-		sql,
-		args...,
-	)
+	values := columnAddrs(set)
+	if version != nil {
+		values[len(values)-1] = next
+	}
+	values = append(values, pks...)
+	if version != nil {
+		values = append(values, current)
+	}
+
+	d := dialectOf(db)
+
+	// soft deleted rows are not updated
+	if softDelete != nil {
+		_, args := notDeleted(d, softDelete, len(values)+1)
+		values = append(values, args...)
+	}
+
+	// only read back anything if there is more than the primary keys; without
+	// RETURNING or OUTPUT the row is only reloaded if ReturnAll is given
+	var returned []*column
+	if columns := returnedColumns(table, o.returnAll, readonlyTag); len(columns) > len(table.PKs) {
+		if o.returnAll || d.Returning() || d.InsertID() == InsertIDOutput {
+			returned = columns
+		}
+	}
+
+	k := sqlKey{d, typ, tableName, op}
+	stmt, err := prepare(ctx, db, k, func() (string, error) {
+		return updateSQL(d, tableName, table, set, version, softDelete, returned)
+	})
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	// whether the row was updated is only determined if required
+	counted := version != nil || o.rowsAffected != nil || o.mustExist
+	affected := int64(1)
+
+	if returned != nil && (d.Returning() || d.InsertID() == InsertIDOutput) {
+		err := stmt.QueryRowContext(ctx, values...).Scan(columnAddrs(returned)...)
+		if err == sql.ErrNoRows {
+			// no row updated, there is nothing to read back
+			affected = 0
+		} else if err != nil {
+			return err
+		}
+		returned = nil
+	} else {
+		res, err := stmt.ExecContext(ctx, values...)
+		if err != nil {
+			return err
+		}
+
+		if counted {
+			if affected, err = res.RowsAffected(); err != nil {
+				return err
+			}
+		}
+	}
 
-	values := append(table.Values(false, false), pks...)
+	if err := checkAffected(affected, version, o); err != nil {
+		return err
+	}
+
+	if version != nil {
+		version.Value.Set(reflect.ValueOf(next))
+	}
 
-	if _, err := db.Exec(query, values...); err != nil {
+	if returned != nil && affected > 0 {
+		err := reload(ctx, db, tableName, typ, table)
+		if err == sql.ErrNoRows {
+			return nil
+		}
 		return err
 	}
 
 	return nil
 }
 
-func Delete(db DB, tableName string, src interface{}) error {
+// Delete deletes the row of src identified by its primary keys. If src has a
+// column tagged version, the row is only deleted if its version still matches
+// the one of src; ErrStaleObject is returned otherwise. If src has a column
+// tagged softdelete, the row is soft deleted instead (see Restore).
+func Delete(db DB, tableName string, src interface{}, opts ...Option) error {
+	return DeleteContext(context.Background(), withContext(db), tableName, src, opts...)
+}
+
+// DeleteContext is like Delete, but uses the given context for the statement.
+func DeleteContext(ctx context.Context, db DBContext, tableName string, src interface{}, opts ...Option) error {
+	return deleteRow(ctx, db, tableName, src, false, applyOptions(opts))
+}
+
+// deleteRow deletes the row of src, soft deleting it if it has a column tagged
+// softdelete and hard is false.
+func deleteRow(ctx context.Context, db DBContext, tableName string, src interface{}, hard bool, o *options) error {
 	table, err := ExtractTable(src)
 	if err != nil {
 		return err
@@ -151,61 +625,457 @@ func Delete(db DB, tableName string, src interface{}) error {
 		return fmt.Errorf("sqlstruct.Delete: primary key column required")
 	}
 
-	sql := "DELETE FROM %s WHERE"
-	args := []interface{}{Quote(tableName)}
+	version, err := versionColumn(table)
+	if err != nil {
+		return fmt.Errorf("sqlstruct.Delete: %v", err)
+	}
+
+	softDelete, err := softDeleteColumn(table)
+	if err != nil {
+		return fmt.Errorf("sqlstruct.Delete: %v", err)
+	}
+
+	if softDelete != nil && !hard {
+		return setDeleted(ctx, db, tableName, reflect.TypeOf(src), table, softDelete, version, true, o)
+	}
+
 	var values []interface{}
+	for _, pk := range table.PKs {
+		values = append(values, pk.Value.Interface())
+	}
+	if version != nil {
+		values = append(values, version.Value.Interface())
+	}
+
+	d := dialectOf(db)
+	k := sqlKey{d, reflect.TypeOf(src), tableName, "delete"}
+	stmt, err := prepare(ctx, db, k, func() (string, error) {
+		return deleteSQL(d, tableName, table, version)
+	})
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, values...)
+	if err != nil {
+		return err
+	}
+
+	if version == nil && o.rowsAffected == nil && !o.mustExist {
+		return nil
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(affected, version, o)
+}
+
+// versionColumn returns the column of table tagged version, or nil if there is
+// none.
+func versionColumn(table *Table) (*column, error) {
+	var version *column
+	for _, col := range table.Columns {
+		if !col.Tags[versionTag] {
+			continue
+		}
+		if version != nil {
+			return nil, fmt.Errorf("multiple version columns")
+		}
+		if !isInt(col.Type) && !isUint(col.Type) {
+			return nil, fmt.Errorf("version field must be an integer; got %v", col.Type)
+		}
+		version = col
+	}
+	return version, nil
+}
+
+// nextVersion returns the successor of the version v.
+func nextVersion(v reflect.Value) interface{} {
+	next := reflect.New(v.Type()).Elem()
+	if isInt(v.Type()) {
+		next.SetInt(v.Int() + 1)
+	} else {
+		next.SetUint(v.Uint() + 1)
+	}
+	return next.Interface()
+}
+
+// checkAffected reports the number of rows affected by a statement writing a
+// row with the given version column, if any, as requested by o. If no row was
+// affected, ErrStaleObject is returned if the row is versioned, and ErrNotFound
+// if requested using MustExist.
+func checkAffected(affected int64, version *column, o *options) error {
+	if o.rowsAffected != nil {
+		*o.rowsAffected = affected
+	}
+
+	if affected > 0 {
+		return nil
+	}
+	if version != nil {
+		return ErrStaleObject
+	}
+	if o.mustExist {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Load loads the row identified by the given primary key values into dst.
+// Composite primary keys are given either as one value per primary key
+// column, in the order of the fields of dst, or as a struct whose fields are
+// named like the primary key columns or fields of dst. Soft deleted rows are
+// not loaded, see LoadDeleted. See also Reload.
+func Load(db DB, tableName string, dst interface{}, keys ...interface{}) error {
+	return LoadContext(context.Background(), withContext(db), tableName, dst, keys...)
+}
+
+// LoadContext is like Load, but uses the given context for the query.
+func LoadContext(ctx context.Context, db DBContext, tableName string, dst interface{}, keys ...interface{}) error {
+	return loadKeys(ctx, db, tableName, dst, keys, &options{})
+}
+
+// LoadDeleted is like Load, but also loads the row if it is soft deleted.
+func LoadDeleted(db DB, tableName string, dst interface{}, keys ...interface{}) error {
+	return LoadDeletedContext(context.Background(), withContext(db), tableName, dst, keys...)
+}
+
+// LoadDeletedContext is like LoadDeleted, but uses the given context for the
+// query.
+func LoadDeletedContext(ctx context.Context, db DBContext, tableName string, dst interface{}, keys ...interface{}) error {
+	return loadKeys(ctx, db, tableName, dst, keys, &options{includeDeleted: true})
+}
+
+// loadKeys loads the row identified by the given primary key values into dst.
+func loadKeys(ctx context.Context, db DBContext, tableName string, dst interface{}, keys []interface{}, o *options) error {
+	table, err := ExtractTable(dst)
+	if err != nil {
+		return err
+	}
+
+	if len(table.PKs) == 0 {
+		return fmt.Errorf("sqlstruct.Load: primary key column required")
+	}
+
+	args, err := keyValues(table, keys)
+	if err != nil {
+		return fmt.Errorf("sqlstruct.Load: %v", err)
+	}
+
+	return loadRow(ctx, db, tableName, dst, table, args, o)
+}
+
+// Reload loads the row identified by the primary keys of dst into dst. Soft
+// deleted rows are not loaded unless IncludeDeleted is given.
+func Reload(db DB, tableName string, dst interface{}, opts ...Option) error {
+	return ReloadContext(context.Background(), withContext(db), tableName, dst, opts...)
+}
+
+// ReloadContext is like Reload, but uses the given context for the query.
+func ReloadContext(ctx context.Context, db DBContext, tableName string, dst interface{}, opts ...Option) error {
+	table, err := ExtractTable(dst)
+	if err != nil {
+		return err
+	}
+
+	if len(table.PKs) == 0 {
+		return fmt.Errorf("sqlstruct.Reload: primary key column required")
+	}
+
+	var args []interface{}
+	for _, pk := range table.PKs {
+		args = append(args, pk.Value.Interface())
+	}
+
+	return loadRow(ctx, db, tableName, dst, table, args, applyOptions(opts))
+}
+
+// loadRow loads the row of table, which was extracted from dst, identified by
+// the given primary key values.
+func loadRow(ctx context.Context, db DBContext, tableName string, dst interface{}, table *Table, args []interface{}, o *options) error {
+	softDelete, err := softDeleteColumn(table)
+	if err != nil {
+		return fmt.Errorf("sqlstruct.Load: %v", err)
+	}
+	if o.includeDeleted {
+		softDelete = nil
+	}
+
+	d := dialectOf(db)
+	op := "load"
+	if o.includeDeleted {
+		op += "-deleted"
+	}
+	k := sqlKey{d, reflect.TypeOf(dst), tableName, op}
+	stmt, err := prepare(ctx, db, k, func() (string, error) {
+		return loadSQL(d, tableName, table, softDelete)
+	})
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if softDelete != nil {
+		_, notDeletedArgs := notDeleted(d, softDelete, len(args)+1)
+		args = append(args, notDeletedArgs...)
+	}
+
+	values := table.Values(true, true)
+
+	if err := stmt.QueryRowContext(ctx, args...).Scan(values...); err != nil {
+		return err
+	}
+
+	trackingOf(dst).snapshot(table.Columns)
+	return nil
+}
+
+// keyValues returns the values of the primary keys of table given to Load,
+// either one per primary key or as a key struct.
+func keyValues(table *Table, keys []interface{}) ([]interface{}, error) {
+	if len(keys) == 1 && len(table.PKs) > 1 {
+		v := reflect.ValueOf(keys[0])
+		if v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.Struct {
+			return keyStructValues(table, v)
+		}
+	}
+
+	if len(keys) != len(table.PKs) {
+		return nil, fmt.Errorf("%d key values given for %d primary key columns", len(keys), len(table.PKs))
+	}
+
+	return keys, nil
+}
+
+// keyStructValues returns the values of the primary keys of table from the
+// fields of the key struct v having the same column or field names.
+func keyStructValues(table *Table, v reflect.Value) ([]interface{}, error) {
+	info, err := fields(v.Type(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	// nil embedded struct pointers are initialized by fieldInfo.value
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	v = c
+
+	values := make([]interface{}, len(table.PKs))
+outer:
+	for i, pk := range table.PKs {
+		for _, f := range info.Fields {
+			if f.Name == pk.Name || f.FieldName == pk.FieldName {
+				fv := f.value(v)
+				if !fv.IsValid() {
+					return nil, fmt.Errorf("key field %v is nil", f.FieldName)
+				}
+				values[i] = fv.Interface()
+				continue outer
+			}
+		}
+		return nil, fmt.Errorf("key struct %v has no field for primary key column %q", v.Type(), pk.Name)
+	}
+
+	return values, nil
+}
+
+func insertSQL(d Dialect, tableName string, columns []*column, rows int, returned []*column) (string, error) {
+	insert, params, err := insertClauses(d, tableName, columns, rows)
+	if err != nil {
+		return "", err
+	}
+
+	if len(returned) == 0 {
+		return insert + params, nil
+	}
+
+	switch d.InsertID() {
+	case InsertIDReturning:
+		return insert + params + returningClause(d, returned), nil
+	case InsertIDOutput:
+		return insert + outputClause(d, returned) + params, nil
+	default:
+		return insert + params, nil
+	}
+}
+
+// returningClause returns the RETURNING clause reading back the given columns.
+func returningClause(d Dialect, columns []*column) string {
+	return " RETURNING " + strings.Join(quoteAll(d, columnNames(columns)), ",")
+}
+
+// outputClause returns the OUTPUT clause reading back the given columns.
+func outputClause(d Dialect, columns []*column) string {
+	names := quoteAll(d, columnNames(columns))
+	for i, name := range names {
+		names[i] = "INSERTED." + name
+	}
+	return " OUTPUT " + strings.Join(names, ",")
+}
+
+// insertClauses returns the INSERT INTO and VALUES clauses inserting the given
+// columns of the given number of rows. Rows without any columns are inserted
+// using DEFAULT VALUES, or () VALUES () for MySQL, one at a time.
+func insertClauses(d Dialect, tableName string, columns []*column, rows int) (string, string, error) {
+	quotedTable, err := quoteTable(d, tableName)
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(columns) == 0 {
+		if rows != 1 {
+			return "", "", fmt.Errorf("sqlstruct.Insert: rows without columns must be inserted one at a time")
+		}
+		if d.Upsert() == UpsertOnDuplicateKey {
+			return "INSERT INTO " + quotedTable + " ()", " VALUES ()", nil
+		}
+		return "INSERT INTO " + quotedTable, " DEFAULT VALUES", nil
+	}
+
+	names := quoteAll(d, columnNames(columns))
+	insert := fmt.Sprintf(
+		"INSERT INTO %s (%s)",
+		quotedTable,
+		strings.Join(names, ","),
+	)
+	tuples := make([]string, rows)
+	for i := range tuples {
+		tuples[i] = "(" + strings.Join(placeholders(d, 1+i*len(names), len(names)), ",") + ")"
+	}
+	params := " VALUES " + strings.Join(tuples, ",")
+
+	return insert, params, nil
+}
+
+// updateSQL builds the statement updating the given columns of the row of
+// table. If version is given, only the row having the current version is
+// updated; if softDelete is given, only a row not soft deleted is updated.
+func updateSQL(d Dialect, tableName string, table *Table, set []*column, version, softDelete *column, returned []*column) (string, error) {
+	quotedTable, err := quoteTable(d, tableName)
+	if err != nil {
+		return "", err
+	}
+
+	columns := quoteAll(d, columnNames(set))
+	params := placeholders(d, 1, len(columns))
+
+	pairs := make([]string, len(columns))
+	for i, _ := range columns {
+		pairs[i] = fmt.Sprintf("%s=%s", columns[i], params[i])
+	}
+
+	sql := "UPDATE %s SET %s"
+	args := []interface{}{quotedTable, strings.Join(pairs, ",")}
+
+	if len(returned) > 0 && d.InsertID() == InsertIDOutput {
+		sql += strings.Replace(outputClause(d, returned), "%", "%%", -1)
+	}
+	sql += " WHERE"
 
 	for i, pk := range table.PKs {
 		if i > 0 {
-			sql += "AND "
+			sql += " AND"
 		}
 		sql += " %s=%s"
-		args = append(args, Quote(pk.Name))
-		args = append(args, Placeholder(1+i))
-		values = append(values, pk.Value.Interface())
+		args = append(args, d.Quote(pk.Name))
+		args = append(args, d.Placeholder(len(columns)+1+i))
+	}
+	if version != nil {
+		sql += " AND %s=%s"
+		args = append(args, d.Quote(version.Name))
+		args = append(args, d.Placeholder(len(columns)+len(table.PKs)+1))
+	}
+	if softDelete != nil {
+		position := len(columns) + len(table.PKs) + 1
+		if version != nil {
+			position++
+		}
+		cond, _ := notDeleted(d, softDelete, position)
+		sql += strings.Replace(cond, "%", "%%", -1)
 	}
 
 	query := fmt.Sprintf(
 		sql,
 		args...,
 	)
+	if len(returned) > 0 && d.Returning() {
+		query += returningClause(d, returned)
+	}
 
-	if _, err := db.Exec(query, values...); err != nil {
-		return err
+	return query, nil
+}
+
+func deleteSQL(d Dialect, tableName string, table *Table, version *column) (string, error) {
+	quotedTable, err := quoteTable(d, tableName)
+	if err != nil {
+		return "", err
 	}
 
-	return nil
+	sql := "DELETE FROM %s WHERE"
+	args := []interface{}{quotedTable}
+
+	for i, pk := range table.PKs {
+		if i > 0 {
+			sql += " AND"
+		}
+		sql += " %s=%s"
+		args = append(args, d.Quote(pk.Name))
+		args = append(args, d.Placeholder(1+i))
+	}
+	if version != nil {
+		sql += " AND %s=%s"
+		args = append(args, d.Quote(version.Name))
+		args = append(args, d.Placeholder(len(table.PKs)+1))
+	}
+
+	return fmt.Sprintf(
+		sql,
+		args...,
+	), nil
 }
 
-func Load(db DB, tableName string, dst interface{}, key interface{}) error {
-	table, err := ExtractTable(dst)
+func loadSQL(d Dialect, tableName string, table *Table, softDelete *column) (string, error) {
+	quotedTable, err := quoteTable(d, tableName)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	if len(table.PKs) == 0 {
-		return fmt.Errorf("sqlstruct.Load: primary key column required")
+	top, limit := d.Limit(1)
+	if top != "" {
+		top += " "
 	}
 
-	sql := "SELECT %s FROM %s WHERE"
-	args := []interface{}{strings.Join(table.QuotedNames(true, true), ","), Quote(tableName)}
+	sql := "SELECT %s%s FROM %s WHERE"
+	args := []interface{}{top, strings.Join(quoteAll(d, table.Names(true, true)), ","), quotedTable}
 
 	for i, pk := range table.PKs {
 		if i > 0 {
-			sql += "AND "
+			sql += " AND"
 		}
 		sql += " %s=%s"
-		args = append(args, Quote(pk.Name))
-		args = append(args, Placeholder(1+i))
+		args = append(args, d.Quote(pk.Name))
+		args = append(args, d.Placeholder(1+i))
 	}
 
 	query := fmt.Sprintf(
 		sql, args...,
 	)
+	if softDelete != nil {
+		cond, _ := notDeleted(d, softDelete, len(table.PKs)+1)
+		query += cond
+	}
+	if limit != "" {
+		query += " " + limit
+	}
 
-	values := table.Values(true, true)
-
-	return db.QueryRow(query, key).Scan(values...)
+	return query, nil
 }
 
 func scanRow(rows *sql.Rows, dst interface{}) error {
@@ -216,46 +1086,108 @@ func scanRow(rows *sql.Rows, dst interface{}) error {
 		return sql.ErrNoRows
 	}
 
-	// TODO: cache!
-	columns, err := rows.Columns()
+	t := reflect.TypeOf(dst)
+	if t.Kind() != reflect.Ptr {
+		return fmt.Errorf("sqlstruct: must be called with a pointer; got %v", t)
+	}
+
+	plan, err := newScanPlan(rows, t.Elem())
 	if err != nil {
 		return err
 	}
 
-	table, err := ExtractTable(dst)
+	return plan.scan(rows, reflect.ValueOf(dst).Elem())
+}
+
+// scanPlan maps the columns of a result set to the fields of a struct type.
+// It is computed once per result set and reused for each of its rows.
+type scanPlan struct {
+	info    *structInfo
+	fields  []int // indexes into info.Fields; -1 for columns not in the struct
+	targets []interface{}
+	discard interface{}
+}
+
+func newScanPlan(rows *sql.Rows, t reflect.Type) (*scanPlan, error) {
+	columns, err := rows.Columns()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// TODO: cache!
-	mapping := map[string]*column{}
-	for _, col := range table.Columns {
-		mapping[col.Name] = col
+	info, err := structInfoOf(t)
+	if err != nil {
+		return nil, err
 	}
 
-	targets := make([]interface{}, len(columns))
+	mapping := map[string]int{}
+	for i, f := range info.Fields {
+		mapping[f.Name] = i
+	}
+
+	plan := &scanPlan{
+		info:    info,
+		fields:  make([]int, len(columns)),
+		targets: make([]interface{}, len(columns)),
+	}
 	for i, name := range columns {
-		if col, ok := mapping[name]; ok {
-			targets[i] = col.Value.Addr().Interface()
+		if idx, ok := mapping[name]; ok {
+			plan.fields[i] = idx
 		} else {
-			targets[i] = new(interface{}) // discard value
+			plan.fields[i] = -1 // discard value
+		}
+	}
+
+	return plan, nil
+}
 
-			// TODO:
-			// if Debug {
-			//   log.Printf("meddler.Targets: column [%s] not found in struct", name)
-			// }
+// scan scans the current row into v, which must be an addressable struct value
+// of the type the plan was computed for.
+func (plan *scanPlan) scan(rows *sql.Rows, v reflect.Value) error {
+	var fieldTargets []interface{}
+	if plan.info.Mapper != nil {
+		fieldTargets = plan.info.Mapper.Targets(v.Addr().Interface())
+	}
+
+	for i, idx := range plan.fields {
+		if idx < 0 {
+			plan.targets[i] = &plan.discard
+			continue
+		}
+
+		f := plan.info.Fields[idx]
+		if pf, ok := f.nullable(v); ok {
+			plan.targets[i] = pf.Addr().Interface()
+		} else if fieldTargets != nil {
+			plan.targets[i] = fieldTargets[idx]
+		} else {
+			plan.targets[i] = f.value(v).Addr().Interface()
 		}
 	}
 
-	if err := rows.Scan(targets...); err != nil {
+	if err := rows.Scan(plan.targets...); err != nil {
 		return err
 	}
 
+	if tracking := plan.info.tracking(v); tracking != nil {
+		var scanned []*column
+		for i, idx := range plan.fields {
+			if idx >= 0 {
+				scanned = append(scanned, &column{Name: plan.info.Fields[idx].Name, Value: reflect.ValueOf(plan.targets[i]).Elem()})
+			}
+		}
+		tracking.snapshot(scanned)
+	}
+
 	return nil
 }
 
 func QueryRow(db DB, dst interface{}, query string, args ...interface{}) error {
-	rows, err := db.Query(query, args...)
+	return QueryRowContext(context.Background(), withContext(db), dst, query, args...)
+}
+
+// QueryRowContext is like QueryRow, but uses the given context for the query.
+func QueryRowContext(ctx context.Context, db DBContext, dst interface{}, query string, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -265,6 +1197,11 @@ func QueryRow(db DB, dst interface{}, query string, args ...interface{}) error {
 }
 
 func QueryAll(db DB, dst interface{}, query string, args ...interface{}) error {
+	return QueryAllContext(context.Background(), withContext(db), dst, query, args...)
+}
+
+// QueryAllContext is like QueryAll, but uses the given context for the query.
+func QueryAllContext(ctx context.Context, db DBContext, dst interface{}, query string, args ...interface{}) error {
 	dstVal := reflect.ValueOf(dst)
 	if dstVal.Kind() != reflect.Ptr {
 		return fmt.Errorf("sqlstruct.QueryAll: must be called with a pointer; got %v", dstVal)
@@ -275,48 +1212,90 @@ func QueryAll(db DB, dst interface{}, query string, args ...interface{}) error {
 		return fmt.Errorf("sqlstruct.QueryAll: must be called with pointer to slice; got %v", sliceVal)
 	}
 
-	ptrType := sliceVal.Type().Elem()
-	if ptrType.Kind() != reflect.Ptr {
-		return fmt.Errorf("sqlstruct.QueryAll: elements must pointers; got %v", ptrType)
+	// elements are either structs or pointers to structs
+	strType := sliceVal.Type().Elem()
+	isPtr := strType.Kind() == reflect.Ptr
+	if isPtr {
+		strType = strType.Elem()
 	}
 
-	strType := ptrType.Elem()
 	if strType.Kind() != reflect.Struct {
-		return fmt.Errorf("sqlstruct.QueryAll: elements must pointers to structs; got %v", strType)
+		return fmt.Errorf("sqlstruct.QueryAll: elements must be structs or pointers to structs; got %v", sliceVal.Type().Elem())
 	}
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 
-	for {
-		// create a new element
-		el := reflect.New(strType)
-		if err := scanRow(rows, el.Interface()); err != nil {
-			if err == sql.ErrNoRows {
-				return nil
+	var plan *scanPlan
+	for rows.Next() {
+		if plan == nil {
+			plan, err = newScanPlan(rows, strType)
+			if err != nil {
+				return err
 			}
-			return err
 		}
 
-		sliceVal.Set(reflect.Append(sliceVal, el))
+		if isPtr {
+			// create a new element
+			el := reflect.New(strType)
+			if err := plan.scan(rows, el.Elem()); err != nil {
+				return err
+			}
+
+			sliceVal.Set(reflect.Append(sliceVal, el))
+		} else {
+			// scan into a new zero element in place, which is dropped again
+			// if scanning fails
+			n := sliceVal.Len()
+			sliceVal.Set(reflect.Append(sliceVal, reflect.Zero(strType)))
+			if err := plan.scan(rows, sliceVal.Index(n)); err != nil {
+				sliceVal.Set(sliceVal.Slice(0, n))
+				return err
+			}
+		}
 	}
+
+	return rows.Err()
 }
 
+// Quote quotes the given identifier using the DefaultDialect.
 func Quote(s string) string {
-	return `"` + s + `"`
+	return DefaultDialect.Quote(s)
 }
 
+// Placeholder returns the n-th bind parameter of the DefaultDialect.
 func Placeholder(n int) string {
-	return "$" + strconv.Itoa(n)
+	return DefaultDialect.Placeholder(n)
 }
 
+// Placeholders returns the first count bind parameters of the DefaultDialect.
 func Placeholders(count int) []string {
-	placeholders := make([]string, count)
-	for i := 0; i < count; i++ {
-		placeholders[i] = Placeholder(i + 1)
+	return placeholders(DefaultDialect, 1, count)
+}
+
+func columnNames(columns []*column) []string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+func isInt(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
 	}
-	return placeholders
+	return false
 }
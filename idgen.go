@@ -0,0 +1,303 @@
+package sqlstruct
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const genTag = "gen"
+
+// IDGenerator generates primary keys on the client. Insert, InsertAll, Upsert,
+// InsertIgnore and InsertValues fill zero primary keys using the generator
+// named by their gen tag, e.g. `sql:"id,pk,gen=uuidv7"`, or registered for
+// their struct type using RegisterIDGenerator.
+//
+// The generated ids are stored into the primary key fields if they are
+// assignable or convertible to them, if the field is a sql.Scanner accepting
+// the driver.Value of the id, or if the field is a string and the id a
+// fmt.Stringer.
+type IDGenerator interface {
+	NewID(ctx context.Context) (interface{}, error)
+}
+
+// IDGeneratorFunc adapts a function to IDGenerator.
+type IDGeneratorFunc func(ctx context.Context) (interface{}, error)
+
+// NewID calls f(ctx).
+func (f IDGeneratorFunc) NewID(ctx context.Context) (interface{}, error) {
+	return f(ctx)
+}
+
+var (
+	// UUIDv4 generates random UUIDs (version 4).
+	UUIDv4 IDGenerator = IDGeneratorFunc(newUUIDv4)
+	// UUIDv7 generates time-ordered UUIDs (version 7).
+	UUIDv7 IDGenerator = IDGeneratorFunc(newUUIDv7)
+	// ULIDs generates time-ordered ULIDs.
+	ULIDs IDGenerator = IDGeneratorFunc(newULID)
+)
+
+var idGenerators sync.Map // map[reflect.Type]IDGenerator
+
+var namedIDGenerators = map[string]IDGenerator{
+	"uuidv4": UUIDv4,
+	"uuidv7": UUIDv7,
+	"ulid":   ULIDs,
+}
+var namedIDGeneratorsMu sync.RWMutex
+
+// RegisterIDGenerator registers the generator used for the zero primary keys of
+// the struct v points to, e.g. RegisterIDGenerator((*User)(nil), UUIDv7).
+// Generators named by gen tags take precedence.
+func RegisterIDGenerator(v interface{}, gen IDGenerator) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("sqlstruct: RegisterIDGenerator must be called with a pointer to a struct; got %v", t))
+	}
+
+	idGenerators.Store(t.Elem(), gen)
+}
+
+// RegisterNamedIDGenerator registers the generator used for primary keys
+// tagged with gen=name. The generators uuidv4, uuidv7 and ulid are built in.
+func RegisterNamedIDGenerator(name string, gen IDGenerator) {
+	namedIDGeneratorsMu.Lock()
+	defer namedIDGeneratorsMu.Unlock()
+	namedIDGenerators[name] = gen
+}
+
+// generateIDs fills the zero primary keys of table, which was extracted from a
+// struct of type t, using their generators.
+func generateIDs(ctx context.Context, t reflect.Type, table *Table) error {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	typeGen, _ := idGenerators.Load(t)
+
+	for _, pk := range table.PKs {
+		if !isZero(pk.Value) {
+			continue
+		}
+
+		var gen IDGenerator
+		if names := tagValues(pk.Tags, genTag); len(names) > 0 {
+			namedIDGeneratorsMu.RLock()
+			gen = namedIDGenerators[names[0]]
+			namedIDGeneratorsMu.RUnlock()
+			if gen == nil {
+				return fmt.Errorf("sqlstruct: unknown id generator %q for field %v", names[0], pk.FieldName)
+			}
+		} else if typeGen != nil {
+			gen = typeGen.(IDGenerator)
+		} else {
+			continue
+		}
+
+		id, err := gen.NewID(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := setID(pk, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setID stores the generated id into the primary key pk. Fields that cannot be
+// bound as statement argument, e.g. plain byte arrays, are rejected, since the
+// row could not be inserted anyway.
+func setID(pk *column, id interface{}) error {
+	v := reflect.ValueOf(id)
+	if !v.IsValid() {
+		return fmt.Errorf("sqlstruct: generated nil id for field %v", pk.FieldName)
+	}
+
+	if _, err := driver.DefaultParameterConverter.ConvertValue(reflect.New(pk.Type).Interface()); err != nil {
+		return fmt.Errorf("sqlstruct: cannot store generated id into field %v of type %v, which does not implement driver.Valuer", pk.FieldName, pk.Type)
+	}
+
+	if v.Type().AssignableTo(pk.Type) {
+		pk.Value.Set(v)
+		return nil
+	}
+
+	// integers are convertible to strings, but not in the intended way
+	if v.Type().ConvertibleTo(pk.Type) && (pk.Type.Kind() == reflect.String) == (v.Kind() == reflect.String) {
+		pk.Value.Set(v.Convert(pk.Type))
+		return nil
+	}
+
+	if scanner, ok := pk.Value.Addr().Interface().(sql.Scanner); ok {
+		if valuer, ok := id.(driver.Valuer); ok {
+			value, err := valuer.Value()
+			if err != nil {
+				return err
+			}
+			return scanner.Scan(value)
+		}
+		return scanner.Scan(id)
+	}
+
+	if s, ok := id.(fmt.Stringer); ok && pk.Type.Kind() == reflect.String {
+		pk.Value.SetString(s.String())
+		return nil
+	}
+
+	return fmt.Errorf("sqlstruct: cannot store generated id of type %T into field %v of type %v", id, pk.FieldName, pk.Type)
+}
+
+// UUID is a UUID generated by UUIDv4 or UUIDv7.
+type UUID [16]byte
+
+// String returns the canonical text form of the UUID.
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// Value implements driver.Valuer using the canonical text form.
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+func newUUIDv4(context.Context) (interface{}, error) {
+	var u UUID
+	if _, err := rand.Read(u[:]); err != nil {
+		return nil, err
+	}
+
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // variant RFC 4122
+	return u, nil
+}
+
+func newUUIDv7(context.Context) (interface{}, error) {
+	var u UUID
+	if _, err := rand.Read(u[6:]); err != nil {
+		return nil, err
+	}
+
+	putMillis(u[:6], now())
+	u[6] = u[6]&0x0f | 0x70 // version 7
+	u[8] = u[8]&0x3f | 0x80 // variant RFC 4122
+	return u, nil
+}
+
+// ULID is a ULID generated by ULIDs.
+type ULID [16]byte
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// String returns the 26 character Crockford base32 form of the ULID.
+func (u ULID) String() string {
+	var buf [26]byte
+	// the 128 bits are encoded as 130 bits with two leading zero bits
+	for i := range buf {
+		var c byte
+		for j := 0; j < 5; j++ {
+			bit := i*5 + j - 2
+			c <<= 1
+			if bit >= 0 && u[bit/8]&(0x80>>uint(bit%8)) != 0 {
+				c |= 1
+			}
+		}
+		buf[i] = crockford[c]
+	}
+	return string(buf[:])
+}
+
+// Value implements driver.Valuer using the text form.
+func (u ULID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+func newULID(context.Context) (interface{}, error) {
+	var u ULID
+	if _, err := rand.Read(u[6:]); err != nil {
+		return nil, err
+	}
+
+	putMillis(u[:6], now())
+	return u, nil
+}
+
+// now is replaced in tests.
+var now = time.Now
+
+// putMillis stores the 48 bit unix timestamp in milliseconds of t into b.
+func putMillis(b []byte, t time.Time) {
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixNano()/int64(time.Millisecond)))
+	copy(b, ms[2:])
+}
+
+// HiLo allocates integer ids in blocks using the hi/lo algorithm, so that
+// only one database round trip is required per block, e.g. for InsertAll.
+// Each hi value obtained from Next, e.g. from a database sequence, reserves the
+// ids hi*Size to hi*Size+Size-1. The id 0 is skipped, since zero primary keys
+// are considered unset.
+//
+//	ids := sqlstruct.NewHiLo(100, func(ctx context.Context) (int64, error) {
+//		var hi int64
+//		err := db.QueryRowContext(ctx, `SELECT nextval('user_hi')`).Scan(&hi)
+//		return hi, err
+//	})
+//	sqlstruct.RegisterIDGenerator((*User)(nil), ids)
+type HiLo struct {
+	size int64
+	next func(ctx context.Context) (int64, error)
+
+	mu     sync.Mutex
+	hi, lo int64
+}
+
+// NewHiLo creates a hi/lo allocator reserving blocks of size ids.
+func NewHiLo(size int64, next func(ctx context.Context) (int64, error)) *HiLo {
+	if size < 1 {
+		size = 1
+	}
+	return &HiLo{size: size, next: next, lo: size}
+}
+
+// NewID returns the next id as int64.
+func (h *HiLo) NewID(ctx context.Context) (interface{}, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for {
+		if h.lo >= h.size {
+			hi, err := h.next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			h.hi, h.lo = hi, 0
+		}
+
+		id := h.hi*h.size + h.lo
+		h.lo++
+		if id != 0 {
+			return id, nil
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package sqlstruct
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestUUIDv4(t *testing.T) {
+	id, err := UUIDv4.NewID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := id.(UUID)
+	if u[6]>>4 != 4 || u[8]>>6 != 2 {
+		t.Errorf("uuid=%v; wanted version 4, variant RFC 4122", u)
+	}
+
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !re.MatchString(u.String()) {
+		t.Errorf("uuid=%v; wanted canonical form", u)
+	}
+}
+
+func TestUUIDv7(t *testing.T) {
+	defer func(fn func() time.Time) { now = fn }(now)
+	now = func() time.Time { return time.Unix(0, 0x0123456789ab*int64(time.Millisecond)) }
+
+	id, err := UUIDv7.NewID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := id.(UUID)
+	if u[6]>>4 != 7 || u[8]>>6 != 2 {
+		t.Errorf("uuid=%v; wanted version 7, variant RFC 4122", u)
+	}
+
+	if s := u.String(); s[:13] != "01234567-89ab" {
+		t.Errorf("uuid=%v; wanted timestamp prefix 01234567-89ab", s)
+	}
+}
+
+func TestULID(t *testing.T) {
+	if s := (ULID{}).String(); s != "00000000000000000000000000" {
+		t.Errorf("ulid=%v; wanted all zeros", s)
+	}
+
+	max := ULID{}
+	for i := range max {
+		max[i] = 0xff
+	}
+	if s := max.String(); s != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("ulid=%v; wanted 7ZZZZZZZZZZZZZZZZZZZZZZZZZ", s)
+	}
+
+	defer func(fn func() time.Time) { now = fn }(now)
+	now = func() time.Time { return time.Unix(1469918176, 385*int64(time.Millisecond)) }
+
+	id, err := ULIDs.NewID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// timestamp of the example in the ULID spec
+	if s := id.(ULID).String(); s[:10] != "01ARYZ6S41" {
+		t.Errorf("ulid=%v; wanted timestamp prefix 01ARYZ6S41", s)
+	}
+}
+
+func TestHiLo(t *testing.T) {
+	his := []int64{3, 7}
+	hilo := NewHiLo(2, func(context.Context) (int64, error) {
+		hi := his[0]
+		his = his[1:]
+		return hi, nil
+	})
+
+	var ids []int64
+	for i := 0; i < 4; i++ {
+		id, err := hilo.NewID(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id.(int64))
+	}
+
+	want := []int64{6, 7, 14, 15}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("ids=%v; wanted %v", ids, want)
+			break
+		}
+	}
+}
+
+func TestHiLoSkipsZero(t *testing.T) {
+	his := []int64{0, 1}
+	hilo := NewHiLo(1, func(context.Context) (int64, error) {
+		hi := his[0]
+		his = his[1:]
+		return hi, nil
+	})
+
+	id, err := hilo.NewID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != int64(1) {
+		t.Errorf("id=%v; wanted 1", id)
+	}
+}
+
+func TestInsertGeneratedTag(t *testing.T) {
+	type Document struct {
+		ID   string `sql:"id,pk,gen=uuidv7"`
+		Name string
+	}
+
+	sqlDB, fake := newFakeDB(t)
+
+	doc := Document{Name: "a"}
+	if err := Insert(WithDialect(sqlDB, MySQL), "document", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.ID) != 36 {
+		t.Errorf("doc.ID=%v; wanted generated uuid", doc.ID)
+	}
+
+	query := fake.last(t)
+	want := "INSERT INTO `document` (`id`,`name`) VALUES (?,?)"
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if query.Args[0] != doc.ID {
+		t.Errorf("args=%v; wanted generated id first", query.Args)
+	}
+}
+
+func TestInsertGeneratedArray(t *testing.T) {
+	type Document struct {
+		ID   uuid `sql:"id,pk,gen=uuidv4"`
+		Name string
+	}
+
+	sqlDB, _ := newFakeDB(t)
+
+	doc := Document{Name: "a"}
+	if err := Insert(WithDialect(sqlDB, MySQL), "document", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.ID[6]>>4 != 4 {
+		t.Errorf("doc.ID=%v; wanted generated uuid", doc.ID)
+	}
+}
+
+func TestInsertGeneratedUnbindable(t *testing.T) {
+	type Document struct {
+		ID   [16]byte `sql:"id,pk,gen=uuidv4"`
+		Name string
+	}
+
+	sqlDB, fake := newFakeDB(t)
+
+	if err := Insert(WithDialect(sqlDB, MySQL), "document", &Document{Name: "a"}); err == nil {
+		t.Error("Expected error for id field that cannot be bound")
+	}
+
+	if len(fake.queries) != 0 {
+		t.Errorf("queries=%v; wanted none", fake.queries)
+	}
+}
+
+func TestInsertGeneratedUnknown(t *testing.T) {
+	type Document struct {
+		ID string `sql:"id,pk,gen=unknown"`
+	}
+
+	db, _ := newFakeDB(t)
+	if err := Insert(db, "document", &Document{}); err == nil {
+		t.Error("Expected error for unknown id generator")
+	}
+}
+
+type hiloUser struct {
+	ID   int64
+	Name string
+}
+
+func TestInsertAllRegisteredGenerator(t *testing.T) {
+	hi := int64(0)
+	RegisterIDGenerator((*hiloUser)(nil), NewHiLo(10, func(context.Context) (int64, error) {
+		hi++
+		return hi, nil
+	}))
+	defer idGenerators.Delete(reflect.TypeOf(hiloUser{}))
+
+	sqlDB, fake := newFakeDB(t)
+
+	users := []*hiloUser{{Name: "a"}, {Name: "b"}, {ID: 99, Name: "c"}}
+	if err := InsertAll(WithDialect(sqlDB, MySQL), "user", users); err != nil {
+		t.Fatal(err)
+	}
+
+	// keys are provided, so a single statement suffices even with LastInsertId
+	if len(fake.queries) != 1 {
+		t.Fatalf("len(queries)=%v; wanted 1", len(fake.queries))
+	}
+
+	want := "INSERT INTO `user` (`id`,`name`) VALUES (?,?),(?,?),(?,?)"
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if users[0].ID != 10 || users[1].ID != 11 || users[2].ID != 99 {
+		t.Errorf("ids=%v,%v,%v; wanted 10,11,99", users[0].ID, users[1].ID, users[2].ID)
+	}
+}
+
+func TestRegisterIDGeneratorNonPointer(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic")
+		}
+	}()
+	RegisterIDGenerator(hiloUser{}, UUIDv4)
+}
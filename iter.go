@@ -0,0 +1,48 @@
+//go:build go1.23
+
+package sqlstruct
+
+import (
+	"context"
+	"iter"
+)
+
+// Iter executes the given query and returns an iterator over its rows, each
+// scanned into a new T. Errors are yielded once as the last element.
+//
+//	for user, err := range sqlstruct.Iter[User](db, `SELECT * FROM "user"`) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func Iter[T any](db DB, query string, args ...interface{}) iter.Seq2[*T, error] {
+	return IterContext[T](context.Background(), withContext(db), query, args...)
+}
+
+// IterContext is like Iter, but uses the given context for the query.
+func IterContext[T any](ctx context.Context, db DBContext, query string, args ...interface{}) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		cur, err := QueryContext(ctx, db, query, args...)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer cur.Close()
+
+		for cur.Next() {
+			dst := new(T)
+			if err := cur.Scan(dst); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(dst, nil) {
+				return
+			}
+		}
+
+		if err := cur.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
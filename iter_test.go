@@ -0,0 +1,48 @@
+//go:build go1.23
+
+package sqlstruct
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIter(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(queryUserRows(3))
+
+	n := 0
+	for user, err := range Iter[queryUser](db, `SELECT * FROM "user"`) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n++
+		if user.ID != n {
+			t.Errorf("user.ID = %v; but want %v", user.ID, n)
+		}
+
+		if n == 2 {
+			break
+		}
+	}
+
+	if n != 2 {
+		t.Errorf("Iterated %v rows; wanted 2", n)
+	}
+}
+
+func TestIterQueryError(t *testing.T) {
+	db, fake := newFakeDB(t)
+	queryErr := errors.New("query failed")
+	fake.push(fakeResult{Err: queryErr})
+
+	for user, err := range Iter[queryUser](db, `SELECT * FROM "user"`) {
+		if err != queryErr {
+			t.Errorf("Expected query error; got %v", err)
+		}
+		if user != nil {
+			t.Errorf("Expected no user; got %v", user)
+		}
+	}
+}
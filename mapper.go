@@ -0,0 +1,68 @@
+package sqlstruct
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Mapper maps the fields of a struct type to its columns without using
+// reflection. Mappers are generated by cmd/sqlstruct-gen and registered using
+// RegisterMapper; all helpers use them automatically.
+type Mapper struct {
+	// Columns are the column names of the struct, in the same order as the
+	// columns of the Table extracted using ExtractTable.
+	Columns []string
+	// Targets returns pointers to the fields of v, which is a pointer to the
+	// struct, in the order of Columns. The pointers serve both as scan targets
+	// and as statement arguments. Nil embedded struct pointers are initialized.
+	Targets func(v interface{}) []interface{}
+}
+
+var mappers sync.Map // map[reflect.Type]*Mapper
+
+// RegisterMapper registers the mapper for the struct v points to, e.g.
+// RegisterMapper((*User)(nil), mapper). It is usually called from the init
+// function of generated code.
+func RegisterMapper(v interface{}, m *Mapper) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("sqlstruct: RegisterMapper must be called with a pointer to a struct; got %v", t))
+	}
+
+	mappers.Store(t.Elem(), m)
+	structInfoCache.Delete(t.Elem())
+}
+
+// checkMapper makes sure that the generated mapper m matches the reflection
+// data of t, i.e. that it is not out of date.
+func checkMapper(t reflect.Type, info *structInfo, m *Mapper) error {
+	if len(m.Columns) != len(info.Fields) {
+		return fmt.Errorf("sqlstruct: mapper of %v is out of date; regenerate it", t)
+	}
+
+	v := reflect.New(t)
+	targets := m.Targets(v.Interface())
+	if len(targets) != len(info.Fields) {
+		return fmt.Errorf("sqlstruct: mapper of %v is out of date; regenerate it", t)
+	}
+
+	for i, f := range info.Fields {
+		if m.Columns[i] != f.Name {
+			return fmt.Errorf("sqlstruct: mapper of %v is out of date; regenerate it", t)
+		}
+
+		target := reflect.ValueOf(targets[i])
+		field := f.value(v.Elem())
+		if field.IsValid() {
+			if target.Kind() != reflect.Ptr || target.IsNil() || target.Pointer() != field.Addr().Pointer() || target.Type().Elem() != field.Type() {
+				return fmt.Errorf("sqlstruct: mapper of %v is out of date; regenerate it", t)
+			}
+		} else if target.Kind() != reflect.Ptr || !target.IsNil() {
+			// nil pointer fields
+			return fmt.Errorf("sqlstruct: mapper of %v is out of date; regenerate it", t)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package sqlstruct
+
+import "testing"
+
+type mappedUser struct {
+	ID   int
+	Name string
+}
+
+type staleUser struct {
+	ID   int
+	Name string
+}
+
+var mappedCalls int
+
+func init() {
+	RegisterMapper((*mappedUser)(nil), &Mapper{
+		Columns: []string{"id", "name"},
+		Targets: func(v interface{}) []interface{} {
+			mappedCalls++
+			s := v.(*mappedUser)
+			return []interface{}{&s.ID, &s.Name}
+		},
+	})
+
+	RegisterMapper((*staleUser)(nil), &Mapper{
+		Columns: []string{"id"},
+		Targets: func(v interface{}) []interface{} {
+			s := v.(*staleUser)
+			return []interface{}{&s.ID}
+		},
+	})
+}
+
+func TestMapperBind(t *testing.T) {
+	user := mappedUser{1, "rkusa"}
+
+	if _, err := ExtractTable(&user); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := mappedCalls
+	table, err := ExtractTable(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mappedCalls != calls+1 {
+		t.Errorf("Expected mapper to be used")
+	}
+
+	table.Columns[1].Value.SetString("rkgo")
+	if user.Name != "rkgo" {
+		t.Errorf("user.Name = %v; but want rkgo", user.Name)
+	}
+}
+
+func TestMapperScan(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(queryUserRows(3))
+
+	if _, err := ExtractTable(&mappedUser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := mappedCalls
+	var users []mappedUser
+	if err := QueryAll(db, &users, `SELECT * FROM "user"`); err != nil {
+		t.Fatal(err)
+	}
+
+	if mappedCalls != calls+3 {
+		t.Errorf("Expected mapper to be used for each row")
+	}
+
+	if len(users) != 3 || users[2].ID != 3 || users[2].Name != "rkusa" {
+		t.Errorf("users=%v; wanted 3 users", users)
+	}
+}
+
+func TestMapperOutOfDate(t *testing.T) {
+	if _, err := ExtractTable(&staleUser{}); err == nil {
+		t.Error("Expected error for out of date mapper")
+	}
+}
+
+func TestRegisterMapperNonStruct(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic")
+		}
+	}()
+
+	RegisterMapper(1, &Mapper{})
+}
@@ -0,0 +1,87 @@
+package sqlstruct
+
+// Option configures optional behaviour of a helper. Options not applicable to
+// a helper are ignored.
+type Option func(*options)
+
+type options struct {
+	conflict       string
+	update         []string
+	loadExisting   bool
+	returnAll      bool
+	rowsAffected   *int64
+	mustExist      bool
+	includeDeleted bool
+}
+
+func applyOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ConflictOn makes Upsert use the columns of the given unique group as
+// conflict target instead of the primary keys, and InsertIgnore only ignore
+// conflicts of the group instead of any unique constraint. Columns are added
+// to a group using the unique tag, e.g. `sql:",unique=tenant_email"`; a plain
+// `sql:",unique"` column forms a group named like the column.
+func ConflictOn(group string) Option {
+	return func(o *options) {
+		o.conflict = group
+	}
+}
+
+// UpdateOnConflict restricts the columns Upsert updates on conflict to the
+// given ones, identified by column or field name.
+func UpdateOnConflict(cols ...string) Option {
+	return func(o *options) {
+		o.update = append([]string{}, cols...)
+	}
+}
+
+// LoadExisting makes InsertIgnore load the existing row into src if it was not
+// inserted. The row is looked up using the columns of the group given by
+// ConflictOn, or using the primary keys.
+func LoadExisting() Option {
+	return func(o *options) {
+		o.loadExisting = true
+	}
+}
+
+// ReturnAll makes Insert, InsertAll, Update, Upsert and InsertIgnore read back
+// all columns of the written rows instead of only generated primary keys and
+// readonly columns. Dialects without RETURNING or OUTPUT only reload the rows
+// using separate queries if ReturnAll is given.
+func ReturnAll() Option {
+	return func(o *options) {
+		o.returnAll = true
+	}
+}
+
+// RowsAffected makes Update and Delete store the number of affected rows into
+// n. Update reports zero rows if Tracking detected no changes and thus no
+// statement was executed. Note that MySQL only counts rows whose values
+// actually changed unless the clientFoundRows connection parameter is set.
+func RowsAffected(n *int64) Option {
+	return func(o *options) {
+		o.rowsAffected = n
+	}
+}
+
+// MustExist makes Update and Delete return ErrNotFound if no row matched the
+// primary keys.
+func MustExist() Option {
+	return func(o *options) {
+		o.mustExist = true
+	}
+}
+
+// IncludeDeleted makes Reload also load soft deleted rows. Use LoadDeleted to
+// load soft deleted rows by key.
+func IncludeDeleted() Option {
+	return func(o *options) {
+		o.includeDeleted = true
+	}
+}
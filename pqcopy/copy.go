@@ -0,0 +1,196 @@
+// Package pqcopy bulk loads structs into PostgreSQL using the COPY FROM STDIN
+// protocol of github.com/lib/pq. It is kept apart from package sqlstruct so
+// that only programs using it link github.com/lib/pq, which registers the
+// "postgres" driver.
+package pqcopy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/lib/pq"
+	"github.com/rkusa/sqlstruct"
+)
+
+// Preparer is implemented by *sql.DB, *sql.Tx and *sql.Conn.
+type Preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// CopyFrom loads all elements of src into the given table using the
+// PostgreSQL COPY FROM STDIN protocol of github.com/lib/pq, which is
+// considerably faster than INSERT for large amounts of rows. src is either a
+// slice of structs or of pointers to structs, or a channel thereof, which is
+// read until it is closed. pq only supports COPY within transactions, so db
+// usually is a *sql.Tx.
+//
+// Columns are omitted like with sqlstruct.Insert, based on the first element;
+// all other elements must omit the same columns. Generated values are not read
+// back. CopyFrom returns the number of rows loaded.
+func CopyFrom(db Preparer, tableName string, src interface{}) (int64, error) {
+	return CopyFromContext(context.Background(), db, tableName, src)
+}
+
+// CopyFromContext is like CopyFrom, but uses the given context for the
+// statement and while waiting for elements of a channel.
+func CopyFromContext(ctx context.Context, db Preparer, tableName string, src interface{}) (int64, error) {
+	next, err := copySource(ctx, src)
+	if err != nil {
+		return 0, fmt.Errorf("pqcopy.CopyFrom: %v", err)
+	}
+
+	first, ok, err := next()
+	if err != nil || !ok {
+		return 0, err
+	}
+
+	columns, values, err := sqlstruct.InsertValues(ctx, first.Addr().Interface())
+	if err != nil {
+		return 0, err
+	}
+
+	query, err := copySQL(tableName, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var n int64
+	for {
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return n, err
+		}
+		n++
+
+		v, ok, err := next()
+		if err != nil {
+			return n, err
+		}
+		if !ok {
+			break
+		}
+
+		var cols []string
+		cols, values, err = sqlstruct.InsertValues(ctx, v.Addr().Interface())
+		if err != nil {
+			return n, err
+		}
+		if !sameNames(cols, columns) {
+			return n, fmt.Errorf("pqcopy.CopyFrom: element %d must set the same primary keys and columns tagged default as the first element", n)
+		}
+	}
+
+	// flush the buffered rows
+	result, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return n, err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		n = affected
+	}
+
+	return n, nil
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// copySQL builds the COPY statement for the given, optionally schema
+// qualified, table.
+func copySQL(tableName string, columns []string) (string, error) {
+	parts, err := sqlstruct.SplitTableName(tableName)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(parts) {
+	case 1:
+		return pq.CopyIn(parts[0], columns...), nil
+	case 2:
+		return pq.CopyInSchema(parts[0], parts[1], columns...), nil
+	default:
+		return "", fmt.Errorf("pqcopy: invalid table name %q", tableName)
+	}
+}
+
+// copySource returns a function yielding the addressable structs of the given
+// slice or channel one after another.
+func copySource(ctx context.Context, src interface{}) (func() (reflect.Value, bool, error), error) {
+	val := reflect.ValueOf(src)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Chan {
+		return nil, fmt.Errorf("must be called with a slice or channel; got %v", reflect.TypeOf(src))
+	}
+
+	strType := val.Type().Elem()
+	isPtr := strType.Kind() == reflect.Ptr
+	if isPtr {
+		strType = strType.Elem()
+	}
+
+	if strType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("elements must be structs or pointers to structs; got %v", val.Type().Elem())
+	}
+
+	i := 0
+	elem := func(el reflect.Value) (reflect.Value, bool, error) {
+		defer func() { i++ }()
+		if isPtr {
+			if el.IsNil() {
+				return reflect.Value{}, false, fmt.Errorf("pqcopy.CopyFrom: element %d is nil", i)
+			}
+			return el.Elem(), true, nil
+		}
+		if !el.CanAddr() {
+			// elements received from channels are not addressable
+			ptr := reflect.New(strType)
+			ptr.Elem().Set(el)
+			el = ptr.Elem()
+		}
+		return el, true, nil
+	}
+
+	if val.Kind() == reflect.Slice {
+		return func() (reflect.Value, bool, error) {
+			if i >= val.Len() {
+				return reflect.Value{}, false, nil
+			}
+			return elem(val.Index(i))
+		}, nil
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: val},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	}
+	return func() (reflect.Value, bool, error) {
+		chosen, el, ok := reflect.Select(cases)
+		if chosen == 1 {
+			return reflect.Value{}, false, ctx.Err()
+		}
+		if !ok {
+			return reflect.Value{}, false, nil
+		}
+		return elem(el)
+	}, nil
+}
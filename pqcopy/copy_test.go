@@ -0,0 +1,156 @@
+package pqcopy
+
+import (
+	"context"
+	"testing"
+)
+
+type batchUser struct {
+	ID      int
+	Name    string
+	Country string
+}
+
+type defaultUser struct {
+	ID     int
+	Name   string
+	Status string `sql:",default"`
+}
+
+func TestCopyFrom(t *testing.T) {
+	db, fake := newFakeDB(t)
+	copyResults(fake, 2)
+
+	users := []batchUser{
+		{Name: "a", Country: "de"},
+		{Name: "b", Country: "at"},
+	}
+	n, err := CopyFrom(db, "user", users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 2 {
+		t.Errorf("n=%v; wanted 2", n)
+	}
+
+	if fake.prepared != 1 {
+		t.Errorf("prepared=%v; wanted 1", fake.prepared)
+	}
+
+	if len(fake.queries) != 3 {
+		t.Fatalf("len(queries)=%v; wanted 3", len(fake.queries))
+	}
+
+	want := `COPY "user" ("name", "country") FROM STDIN`
+	for _, query := range fake.queries {
+		if query.Query != want {
+			t.Errorf("query=%v; wanted %v", query.Query, want)
+		}
+	}
+
+	if args := fake.queries[1].Args; len(args) != 2 || args[0] != "b" || args[1] != "at" {
+		t.Errorf("args=%v; wanted [b at]", args)
+	}
+
+	if args := fake.queries[2].Args; len(args) != 0 {
+		t.Errorf("args=%v; wanted none to flush", args)
+	}
+}
+
+func TestCopyFromChannel(t *testing.T) {
+	db, fake := newFakeDB(t)
+	copyResults(fake, 3)
+
+	users := make(chan *batchUser)
+	go func() {
+		defer close(users)
+		for i := 1; i <= 3; i++ {
+			users <- &batchUser{ID: i, Name: "rkusa"}
+		}
+	}()
+
+	n, err := CopyFrom(db, "public.user", users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 3 {
+		t.Errorf("n=%v; wanted 3", n)
+	}
+
+	want := `COPY "public"."user" ("id", "name", "country") FROM STDIN`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if args := fake.queries[2].Args; len(args) != 3 || args[0] != int64(3) {
+		t.Errorf("args=%v; wanted [3 rkusa ]", args)
+	}
+}
+
+func TestCopyFromChannelCanceled(t *testing.T) {
+	db, _ := newFakeDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := CopyFromContext(ctx, db, "user", make(chan batchUser)); err != context.Canceled {
+		t.Errorf("err=%v; wanted %v", err, context.Canceled)
+	}
+}
+
+func TestCopyFromEmpty(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	n, err := CopyFrom(db, "user", []batchUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 0 || len(fake.queries) != 0 {
+		t.Errorf("n=%v, queries=%v; wanted none", n, fake.queries)
+	}
+}
+
+func TestCopyFromMixedPKs(t *testing.T) {
+	db, _ := newFakeDB(t)
+
+	users := []batchUser{{ID: 1, Name: "a"}, {Name: "b"}}
+	if _, err := CopyFrom(db, "user", users); err == nil {
+		t.Error("Expected error for elements with and without primary keys")
+	}
+}
+
+func TestCopyFromInvalid(t *testing.T) {
+	db, _ := newFakeDB(t)
+
+	if _, err := CopyFrom(db, "user", batchUser{}); err == nil {
+		t.Error("Expected error when copying a struct")
+	}
+
+	for _, name := range []string{"a.b.c", "audit..events"} {
+		if _, err := CopyFrom(db, name, []batchUser{{}}); err == nil {
+			t.Errorf("Expected error for invalid table name %q", name)
+		}
+	}
+}
+
+func TestCopyFromDefault(t *testing.T) {
+	db, fake := newFakeDB(t)
+	copyResults(fake, 1)
+
+	if _, err := CopyFrom(db, "user", []defaultUser{{Name: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `COPY "user" ("name") FROM STDIN`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	users := []defaultUser{{Name: "a"}, {Name: "b", Status: "blocked"}}
+	if _, err := CopyFrom(db, "user", users); err == nil {
+		t.Error("Expected error for elements omitting different columns")
+	}
+}
@@ -0,0 +1,117 @@
+package pqcopy
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"testing"
+)
+
+// fakeDB is an in-memory database/sql driver that records every statement
+// executed and answers them with queued results.
+type fakeDB struct {
+	mu       sync.Mutex
+	queries  []fakeQuery
+	results  []int64 // rows affected
+	prepared int
+}
+
+type fakeQuery struct {
+	Query string
+	Args  []driver.Value
+}
+
+func newFakeDB(t testing.TB) (*sql.DB, *fakeDB) {
+	fake := &fakeDB{}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+// copyResults queues the results of copying n rows: one per row and the final
+// flush reporting the rows loaded.
+func copyResults(fake *fakeDB, n int) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for i := 0; i < n; i++ {
+		fake.results = append(fake.results, 0)
+	}
+	fake.results = append(fake.results, int64(n))
+}
+
+func (f *fakeDB) last(t testing.TB) fakeQuery {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.queries) == 0 {
+		t.Fatal("no statement executed")
+	}
+	return f.queries[len(f.queries)-1]
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{f}, nil
+}
+
+func (f *fakeDB) Driver() driver.Driver {
+	return fakeDriver{f}
+}
+
+type fakeDriver struct {
+	db *fakeDB
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{d.db}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.mu.Lock()
+	c.db.prepared++
+	c.db.mu.Unlock()
+	return &fakeStmt{c.db, query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.queries = append(s.db.queries, fakeQuery{s.query, args})
+
+	var affected int64
+	if len(s.db.results) > 0 {
+		affected = s.db.results[0]
+		s.db.results = s.db.results[1:]
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, driver.ErrSkip
+}
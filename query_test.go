@@ -0,0 +1,161 @@
+package sqlstruct
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+type queryUser struct {
+	ID           int
+	Name         string
+	QueryAddress `sql:"address"`
+}
+
+type QueryAddress struct {
+	City string
+}
+
+func queryUserRows(n int) fakeResult {
+	res := fakeResult{Columns: []string{"id", "name", "unknown", "address_city"}}
+	for i := 0; i < n; i++ {
+		res.Rows = append(res.Rows, []driver.Value{int64(i + 1), "rkusa", "x", "Dresden"})
+	}
+	return res
+}
+
+func TestQueryAll(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(queryUserRows(3))
+
+	var users []*queryUser
+	if err := QueryAll(db, &users, `SELECT * FROM "user"`); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 3 {
+		t.Fatalf("len(users)=%v; wanted 3", len(users))
+	}
+
+	for i, user := range users {
+		if user.ID != i+1 {
+			t.Errorf("user.ID = %v; but want %v", user.ID, i+1)
+		}
+		if user.Name != "rkusa" || user.City != "Dresden" {
+			t.Errorf("user=%v; wanted {%v rkusa {Dresden}}", user, i+1)
+		}
+	}
+}
+
+func TestQueryAllStructs(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(queryUserRows(3))
+
+	var users []queryUser
+	if err := QueryAll(db, &users, `SELECT * FROM "user"`); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 3 {
+		t.Fatalf("len(users)=%v; wanted 3", len(users))
+	}
+
+	if users[2].ID != 3 || users[2].City != "Dresden" {
+		t.Errorf("user=%v; wanted {3 rkusa {Dresden}}", users[2])
+	}
+}
+
+func TestQueryAllStructsScanError(t *testing.T) {
+	db, fake := newFakeDB(t)
+	res := queryUserRows(2)
+	res.Rows[1][0] = "invalid"
+	fake.push(res)
+
+	var users []queryUser
+	if err := QueryAll(db, &users, `SELECT * FROM "user"`); err == nil {
+		t.Fatal("Expected scan error")
+	}
+
+	if len(users) != 1 {
+		t.Errorf("len(users)=%v; wanted 1, without the partly scanned element", len(users))
+	}
+}
+
+func TestQueryAllEmpty(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(queryUserRows(0))
+
+	var users []*queryUser
+	if err := QueryAll(db, &users, `SELECT * FROM "user"`); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 0 {
+		t.Errorf("len(users)=%v; wanted 0", len(users))
+	}
+}
+
+func TestQueryRow(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(queryUserRows(2))
+
+	user := queryUser{}
+	if err := QueryRow(db, &user, `SELECT * FROM "user"`); err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != 1 || user.City != "Dresden" {
+		t.Errorf("user=%v; wanted {1 rkusa {Dresden}}", user)
+	}
+
+	fake.push(queryUserRows(0))
+	if err := QueryRow(db, &user, `SELECT * FROM "user"`); err != sql.ErrNoRows {
+		t.Errorf("Expected no rows error; got %v", err)
+	}
+}
+
+func BenchmarkQueryAll(b *testing.B) {
+	const n = 1000
+	db, fake := newFakeDB(b)
+	res := queryUserRows(n)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fake.push(res)
+		users := make([]*queryUser, 0, n)
+		if err := QueryAll(db, &users, `SELECT * FROM "user"`); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*n)/b.Elapsed().Seconds(), "rows/s")
+}
+
+// BenchmarkScanRowPerRow computes the column mapping for every single row,
+// which QueryAll avoids by reusing its scan plan.
+func BenchmarkScanRowPerRow(b *testing.B) {
+	const n = 1000
+	db, fake := newFakeDB(b)
+	res := queryUserRows(n)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fake.push(res)
+		rows, err := db.Query(`SELECT * FROM "user"`)
+		if err != nil {
+			b.Fatal(err)
+		}
+		users := make([]*queryUser, 0, n)
+		for {
+			user := &queryUser{}
+			if err := scanRow(rows, user); err != nil {
+				if err == sql.ErrNoRows {
+					break
+				}
+				b.Fatal(err)
+			}
+			users = append(users, user)
+		}
+		rows.Close()
+	}
+	b.ReportMetric(float64(b.N*n)/b.Elapsed().Seconds(), "rows/s")
+}
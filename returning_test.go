@@ -0,0 +1,232 @@
+package sqlstruct
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+type returningUser struct {
+	ID      int
+	Name    string
+	Created time.Time `sql:",readonly"`
+	Slug    string    `sql:",readonly"`
+}
+
+var returningTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestInsertReturningReadonly(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "created", "slug"},
+		Rows:    [][]driver.Value{{int64(1), returningTime, "rkusa"}},
+	})
+
+	user := returningUser{Name: "rkusa"}
+	if err := Insert(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INSERT INTO "user" ("name") VALUES ($1) RETURNING "id","created","slug"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.ID != 1 || !user.Created.Equal(returningTime) || user.Slug != "rkusa" {
+		t.Errorf("user=%v; wanted readonly columns to be read back", user)
+	}
+}
+
+func TestInsertReturningAll(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "name", "created", "slug"},
+		Rows:    [][]driver.Value{{int64(1), "RKUSA", returningTime, "rkusa"}},
+	})
+
+	user := returningUser{Name: "rkusa"}
+	if err := Insert(db, "user", &user, ReturnAll()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INSERT INTO "user" ("name") VALUES ($1) RETURNING "id","name","created","slug"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.Name != "RKUSA" {
+		t.Errorf("user.Name=%v; wanted RKUSA", user.Name)
+	}
+}
+
+func TestInsertAllReturningReadonly(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "created", "slug"},
+		Rows: [][]driver.Value{
+			{int64(1), returningTime, "a"},
+			{int64(2), returningTime, "b"},
+		},
+	})
+
+	users := []returningUser{{Name: "a"}, {Name: "b"}}
+	if err := InsertAll(db, "user", users); err != nil {
+		t.Fatal(err)
+	}
+
+	if users[1].ID != 2 || users[1].Slug != "b" {
+		t.Errorf("users[1]=%v; wanted readonly columns to be read back", users[1])
+	}
+}
+
+func TestInsertReturningSQLServer(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "created", "slug"},
+		Rows:    [][]driver.Value{{int64(1), returningTime, "rkusa"}},
+	})
+
+	user := returningUser{Name: "rkusa"}
+	if err := Insert(WithDialect(sqlDB, SQLServer), "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INSERT INTO [user] ([name]) OUTPUT INSERTED.[id],INSERTED.[created],INSERTED.[slug] VALUES (@p1)`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.Slug != "rkusa" {
+		t.Errorf("user.Slug=%v; wanted rkusa", user.Slug)
+	}
+}
+
+func TestInsertReturningLastInsertID(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	db := WithDialect(sqlDB, MySQL)
+	fake.push(fakeResult{LastInsertID: 2})
+
+	// without ReturnAll only the generated key is read back
+	user := returningUser{Name: "rkusa"}
+	if err := Insert(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 1 {
+		t.Fatalf("len(queries)=%v; wanted 1", len(fake.queries))
+	}
+
+	if user.ID != 2 || user.Slug != "" {
+		t.Errorf("user=%v; wanted only the key to be read back", user)
+	}
+
+	fake.push(fakeResult{LastInsertID: 3}, fakeResult{
+		Columns: []string{"id", "name", "created", "slug"},
+		Rows:    [][]driver.Value{{int64(3), "rkusa", returningTime, "rkusa"}},
+	})
+
+	user = returningUser{Name: "rkusa"}
+	if err := Insert(db, "user", &user, ReturnAll()); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := "SELECT `id`,`name`,`created`,`slug` FROM `user` WHERE `id`=? LIMIT 1"
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 1 || query.Args[0] != int64(3) {
+		t.Errorf("args=%v; wanted [3]", query.Args)
+	}
+
+	if user.ID != 3 || user.Slug != "rkusa" {
+		t.Errorf("user=%v; wanted reloaded row", user)
+	}
+}
+
+func TestUpdateReturningReadonly(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "created", "slug"},
+		Rows:    [][]driver.Value{{int64(1), returningTime, "rkusa"}},
+	})
+
+	user := returningUser{ID: 1, Name: "rkusa"}
+	if err := Update(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `UPDATE "user" SET "name"=$1 WHERE "id"=$2 RETURNING "id","created","slug"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.Slug != "rkusa" {
+		t.Errorf("user.Slug=%v; wanted rkusa", user.Slug)
+	}
+}
+
+func TestUpdateReturningNoRow(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{Columns: []string{"id", "created", "slug"}})
+
+	if err := Update(db, "user", &returningUser{ID: 1}); err != nil {
+		t.Errorf("err=%v; wanted none", err)
+	}
+}
+
+func TestUpdateReturningSQLServer(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "name", "created", "slug"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa", returningTime, "rkusa"}},
+	})
+
+	user := returningUser{ID: 1, Name: "rkusa"}
+	if err := Update(WithDialect(sqlDB, SQLServer), "user", &user, ReturnAll()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `UPDATE [user] SET [name]=@p1 OUTPUT INSERTED.[id],INSERTED.[name],INSERTED.[created],INSERTED.[slug] WHERE [id]=@p2`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestUpdateReturningLastInsertID(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	db := WithDialect(sqlDB, SQLite)
+
+	// without ReturnAll the row is not reloaded
+	user := returningUser{ID: 1, Name: "rkusa"}
+	if err := Update(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 1 {
+		t.Fatalf("len(queries)=%v; wanted 1", len(fake.queries))
+	}
+
+	fake.push(fakeResult{RowsAffected: 1}, fakeResult{
+		Columns: []string{"id", "name", "created", "slug"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa", returningTime, "rkusa"}},
+	})
+
+	if err := Update(db, "user", &user, ReturnAll()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 3 {
+		t.Fatalf("len(queries)=%v; wanted 3", len(fake.queries))
+	}
+
+	want := `UPDATE "user" SET "name"=? WHERE "id"=?`
+	if q := fake.queries[1].Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.Slug != "rkusa" {
+		t.Errorf("user.Slug=%v; wanted rkusa", user.Slug)
+	}
+}
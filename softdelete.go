@@ -0,0 +1,250 @@
+package sqlstruct
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timePtrType  = reflect.TypeOf((*time.Time)(nil))
+	nullTimeType = reflect.TypeOf(sql.NullTime{})
+)
+
+// HardDelete is like Delete, but deletes the row of src even if it has a
+// column tagged softdelete.
+func HardDelete(db DB, tableName string, src interface{}, opts ...Option) error {
+	return HardDeleteContext(context.Background(), withContext(db), tableName, src, opts...)
+}
+
+// HardDeleteContext is like HardDelete, but uses the given context for the
+// statement.
+func HardDeleteContext(ctx context.Context, db DBContext, tableName string, src interface{}, opts ...Option) error {
+	return deleteRow(ctx, db, tableName, src, true, applyOptions(opts))
+}
+
+// Restore restores the soft deleted row of src identified by its primary keys.
+//
+// Rows are soft deleted by Delete if they have a bool, *time.Time or
+// sql.NullTime column tagged softdelete, e.g. `sql:"deleted_at,softdelete"`.
+// Delete then sets the column to true or the current time of the database
+// instead of deleting the row, and Load ignores the row; use LoadDeleted to
+// load it anyway. The time is read back like readonly columns. Update and
+// UpdateColumns neither change the column nor update soft deleted rows.
+// Restore sets the column back to false or NULL. Like Delete, Restore honours
+// the version column and the options RowsAffected, MustExist and ReturnAll.
+func Restore(db DB, tableName string, src interface{}, opts ...Option) error {
+	return RestoreContext(context.Background(), withContext(db), tableName, src, opts...)
+}
+
+// RestoreContext is like Restore, but uses the given context for the
+// statement.
+func RestoreContext(ctx context.Context, db DBContext, tableName string, src interface{}, opts ...Option) error {
+	o := applyOptions(opts)
+
+	table, err := ExtractTable(src)
+	if err != nil {
+		return err
+	}
+
+	if len(table.PKs) == 0 {
+		return fmt.Errorf("sqlstruct.Restore: primary key column required")
+	}
+
+	version, err := versionColumn(table)
+	if err != nil {
+		return fmt.Errorf("sqlstruct.Restore: %v", err)
+	}
+
+	softDelete, err := softDeleteColumn(table)
+	if err != nil {
+		return fmt.Errorf("sqlstruct.Restore: %v", err)
+	}
+	if softDelete == nil {
+		return fmt.Errorf("sqlstruct.Restore: no column tagged softdelete")
+	}
+
+	return setDeleted(ctx, db, tableName, reflect.TypeOf(src), table, softDelete, version, false, o)
+}
+
+// softDeleteColumn returns the column of table tagged softdelete, or nil if
+// there is none.
+func softDeleteColumn(table *Table) (*column, error) {
+	var softDelete *column
+	for _, col := range table.Columns {
+		if !col.Tags[softDeleteTag] {
+			continue
+		}
+		if softDelete != nil {
+			return nil, fmt.Errorf("multiple softdelete columns")
+		}
+		// times must be nullable, NULL marks rows as not deleted
+		if col.Type != timePtrType && col.Type != nullTimeType && col.Type.Kind() != reflect.Bool {
+			return nil, fmt.Errorf("softdelete field must be a bool, *time.Time or sql.NullTime; got %v", col.Type)
+		}
+		softDelete = col
+	}
+	return softDelete, nil
+}
+
+// isTimestamp reports whether the softdelete column col holds the time the row
+// was deleted, which is set by the database.
+func isTimestamp(col *column) bool {
+	return col.Type.Kind() != reflect.Bool
+}
+
+// notDeleted returns the condition, starting with AND, excluding the rows
+// marked as deleted by the softdelete column col, and its arguments, starting
+// at the placeholder with the given position.
+func notDeleted(d Dialect, col *column, position int) (string, []interface{}) {
+	if col.Type == nullTimeType || col.Type == timePtrType {
+		return " AND " + d.Quote(col.Name) + " IS NULL", nil
+	}
+	return " AND " + d.Quote(col.Name) + "=" + d.Placeholder(position), []interface{}{reflect.Zero(col.Type).Interface()}
+}
+
+// setDeleted soft deletes or restores the row of table, which was extracted
+// from a struct of type typ, by updating its softdelete column col. Rows
+// already soft deleted are not deleted again.
+func setDeleted(ctx context.Context, db DBContext, tableName string, typ reflect.Type, table *Table, col, version *column, deleted bool, o *options) error {
+	d := dialectOf(db)
+
+	// deletion times are set by the database, other values are bound
+	var value interface{}
+	var values []interface{}
+	if !deleted {
+		value = reflect.Zero(col.Type).Interface()
+		values = append(values, value)
+	} else if !isTimestamp(col) {
+		value = reflect.ValueOf(true).Convert(col.Type).Interface()
+		values = append(values, value)
+	}
+	bound := len(values) > 0
+
+	for _, pk := range table.PKs {
+		values = append(values, pk.Value.Interface())
+	}
+	if version != nil {
+		values = append(values, version.Value.Interface())
+	}
+
+	op := "restore"
+	if deleted {
+		op = "soft-delete"
+		_, args := notDeleted(d, col, len(values)+1)
+		values = append(values, args...)
+	}
+
+	var returned []*column
+	if o.returnAll {
+		returned = returnedColumns(table, true)
+		op += "-all"
+	} else if value == nil {
+		returned = []*column{col}
+	}
+
+	output := d.Returning() || d.InsertID() == InsertIDOutput
+	if !output && !o.returnAll {
+		// reloading the row is opt-in
+		returned = nil
+	}
+
+	k := sqlKey{d, typ, tableName, op}
+	stmt, err := prepare(ctx, db, k, func() (string, error) {
+		return setDeletedSQL(d, tableName, table, col, version, deleted, bound, returned)
+	})
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	counted := version != nil || o.rowsAffected != nil || o.mustExist
+	affected := int64(1)
+
+	if returned != nil && output {
+		err := stmt.QueryRowContext(ctx, values...).Scan(columnAddrs(returned)...)
+		if err == sql.ErrNoRows {
+			// no row updated, there is nothing to read back
+			affected = 0
+		} else if err != nil {
+			return err
+		}
+		returned = nil
+	} else {
+		res, err := stmt.ExecContext(ctx, values...)
+		if err != nil {
+			return err
+		}
+
+		if counted {
+			if affected, err = res.RowsAffected(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := checkAffected(affected, version, o); err != nil {
+		return err
+	}
+
+	if value != nil && affected > 0 {
+		col.Value.Set(reflect.ValueOf(value))
+	}
+
+	if returned != nil && affected > 0 {
+		err := reload(ctx, db, tableName, typ, table)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// setDeletedSQL builds the statement soft deleting or restoring the row of
+// table by setting its softdelete column col, either to a bound value or to the
+// current time of the database.
+func setDeletedSQL(d Dialect, tableName string, table *Table, col, version *column, deleted, bound bool, returned []*column) (string, error) {
+	quotedTable, err := quoteTable(d, tableName)
+	if err != nil {
+		return "", err
+	}
+
+	position := 1
+	value := d.CurrentTimestamp()
+	if bound {
+		value = d.Placeholder(position)
+		position++
+	}
+
+	query := "UPDATE " + quotedTable + " SET " + d.Quote(col.Name) + "=" + value
+	if len(returned) > 0 && d.InsertID() == InsertIDOutput {
+		query += outputClause(d, returned)
+	}
+
+	conditions := make([]string, 0, len(table.PKs)+1)
+	for _, pk := range table.PKs {
+		conditions = append(conditions, d.Quote(pk.Name)+"="+d.Placeholder(position))
+		position++
+	}
+	if version != nil {
+		conditions = append(conditions, d.Quote(version.Name)+"="+d.Placeholder(position))
+		position++
+	}
+	query += " WHERE " + strings.Join(conditions, " AND ")
+
+	if deleted {
+		cond, _ := notDeleted(d, col, position)
+		query += cond
+	}
+
+	if len(returned) > 0 && d.Returning() {
+		query += returningClause(d, returned)
+	}
+
+	return query, nil
+}
@@ -0,0 +1,421 @@
+package sqlstruct
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+type softUser struct {
+	ID        int
+	Name      string
+	DeletedAt sql.NullTime `sql:"deleted_at,softdelete"`
+}
+
+func TestSoftDelete(t *testing.T) {
+	deletedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"deleted_at"},
+		Rows:    [][]driver.Value{{deletedAt}},
+	})
+
+	user := softUser{ID: 1, Name: "rkusa"}
+	if err := Delete(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `UPDATE "user" SET "deleted_at"=CURRENT_TIMESTAMP WHERE "id"=$1 AND "deleted_at" IS NULL RETURNING "deleted_at"`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 1 || query.Args[0] != int64(1) {
+		t.Errorf("args=%v; wanted [1]", query.Args)
+	}
+
+	if !user.DeletedAt.Valid || !user.DeletedAt.Time.Equal(deletedAt) {
+		t.Errorf("user.DeletedAt=%v; wanted %v", user.DeletedAt, deletedAt)
+	}
+
+	// already deleted
+	fake.push(fakeResult{Columns: []string{"deleted_at"}})
+	if err := Delete(db, "user", &user, MustExist()); err != ErrNotFound {
+		t.Errorf("err=%v; wanted ErrNotFound", err)
+	}
+}
+
+func TestSoftDeleteLoad(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "name", "deleted_at"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa", nil}},
+	}, fakeResult{
+		Columns: []string{"id", "name", "deleted_at"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa", time.Now()}},
+	}, fakeResult{
+		Columns: []string{"id", "name", "deleted_at"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa", time.Now()}},
+	})
+
+	var user softUser
+	if err := Load(db, "user", &user, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `SELECT "id","name","deleted_at" FROM "user" WHERE "id"=$1 AND "deleted_at" IS NULL LIMIT 1`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if err := LoadDeleted(db, "user", &user, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want = `SELECT "id","name","deleted_at" FROM "user" WHERE "id"=$1 LIMIT 1`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 1 {
+		t.Errorf("args=%v; wanted [1]", query.Args)
+	}
+
+	if !user.DeletedAt.Valid {
+		t.Error("Expected deleted row to be loaded")
+	}
+
+	if err := Reload(db, "user", &user, IncludeDeleted()); err != nil {
+		t.Fatal(err)
+	}
+
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestSoftDeleteUpdate(t *testing.T) {
+	type Doc struct {
+		ID        int
+		Name      string
+		Slug      string       `sql:",readonly"`
+		DeletedAt sql.NullTime `sql:"deleted_at,softdelete"`
+	}
+
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "slug"},
+		Rows:    [][]driver.Value{{int64(1), "a"}},
+	})
+
+	doc := Doc{ID: 1, Name: "a"}
+	if err := Update(db, "doc", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `UPDATE "doc" SET "name"=$1 WHERE "id"=$2 AND "deleted_at" IS NULL RETURNING "id","slug"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	// soft deleted rows are not updated
+	fake.push(fakeResult{RowsAffected: 0})
+	if err := Update(db, "user", &softUser{ID: 1, Name: "rkusa"}, MustExist()); err != ErrNotFound {
+		t.Errorf("err=%v; wanted ErrNotFound", err)
+	}
+
+	want = `UPDATE "user" SET "name"=$1 WHERE "id"=$2 AND "deleted_at" IS NULL`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if err := UpdateColumns(db, "user", &softUser{ID: 1}, []string{"deleted_at"}); err == nil {
+		t.Error("Expected error when updating the softdelete column")
+	}
+}
+
+func TestSoftDeleteUpsert(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id"},
+		Rows:    [][]driver.Value{{int64(1)}},
+	})
+
+	// soft deleted rows are not restored by the update
+	if err := Upsert(db, "user", &softUser{ID: 1, Name: "rkusa"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INSERT INTO "user" ("id","name","deleted_at") VALUES ($1,$2,$3)` +
+		` ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name" RETURNING "id"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if err := Upsert(db, "user", &softUser{ID: 1}, UpdateOnConflict("deleted_at")); err == nil {
+		t.Error("Expected error when updating the softdelete column on conflict")
+	}
+}
+
+func TestSoftDeleteRestore(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	user := softUser{ID: 1, DeletedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	if err := Restore(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `UPDATE "user" SET "deleted_at"=$1 WHERE "id"=$2`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 2 || query.Args[0] != nil {
+		t.Errorf("args=%v; wanted [<nil> 1]", query.Args)
+	}
+
+	if user.DeletedAt.Valid {
+		t.Errorf("user.DeletedAt=%v; wanted NULL", user.DeletedAt)
+	}
+
+	if err := Restore(db, "user", &stmtUser{ID: 1}); err == nil {
+		t.Error("Expected error for struct without softdelete column")
+	}
+}
+
+func TestHardDelete(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	if err := HardDelete(db, "user", &softUser{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `DELETE FROM "user" WHERE "id"=$1`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestSoftDeleteBool(t *testing.T) {
+	type Doc struct {
+		ID      int
+		Deleted bool `sql:",softdelete"`
+		Version int  `sql:",version"`
+	}
+
+	sqlDB, fake := newFakeDB(t)
+	db := WithDialect(sqlDB, MySQL)
+
+	doc := Doc{ID: 1, Version: 2}
+	if err := Delete(db, "doc", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := "UPDATE `doc` SET `deleted`=? WHERE `id`=? AND `version`=? AND `deleted`=?"
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 4 || query.Args[0] != true || query.Args[2] != int64(2) || query.Args[3] != false {
+		t.Errorf("args=%v; wanted [true 1 2 false]", query.Args)
+	}
+
+	if !doc.Deleted {
+		t.Error("Expected doc to be marked as deleted")
+	}
+
+	fake.push(fakeResult{
+		Columns: []string{"id", "deleted", "version"},
+		Rows:    [][]driver.Value{{int64(1), false, int64(2)}},
+	})
+	if err := Reload(db, "doc", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	query = fake.last(t)
+	want = "SELECT `id`,`deleted`,`version` FROM `doc` WHERE `id`=? AND `deleted`=? LIMIT 1"
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 2 || query.Args[1] != false {
+		t.Errorf("args=%v; wanted [1 false]", query.Args)
+	}
+}
+
+func TestSoftDeleteReturnAll(t *testing.T) {
+	type Doc struct {
+		ID        int
+		DeletedAt sql.NullTime `sql:"deleted_at,softdelete"`
+	}
+
+	sqlDB, fake := newFakeDB(t)
+	db := WithDialect(sqlDB, MySQL)
+
+	// without RETURNING the time is only read back with ReturnAll
+	doc := Doc{ID: 1}
+	if err := Delete(db, "doc", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := "UPDATE `doc` SET `deleted_at`=CURRENT_TIMESTAMP(6) WHERE `id`=? AND `deleted_at` IS NULL"
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 1 || query.Args[0] != int64(1) {
+		t.Errorf("args=%v; wanted [1]", query.Args)
+	}
+
+	if doc.DeletedAt.Valid {
+		t.Errorf("doc.DeletedAt=%v; wanted it not to be read back", doc.DeletedAt)
+	}
+
+	deletedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fake.push(fakeResult{RowsAffected: 1}, fakeResult{
+		Columns: []string{"id", "deleted_at"},
+		Rows:    [][]driver.Value{{int64(1), deletedAt}},
+	})
+
+	if err := Delete(db, "doc", &doc, ReturnAll()); err != nil {
+		t.Fatal(err)
+	}
+
+	want = "SELECT `id`,`deleted_at` FROM `doc` WHERE `id`=? LIMIT 1"
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if !doc.DeletedAt.Valid || !doc.DeletedAt.Time.Equal(deletedAt) {
+		t.Errorf("doc.DeletedAt=%v; wanted %v", doc.DeletedAt, deletedAt)
+	}
+}
+
+func TestSoftDeleteTimePtr(t *testing.T) {
+	type Doc struct {
+		ID        int
+		DeletedAt *time.Time `sql:"deleted_at,softdelete"`
+	}
+
+	sqlDB, fake := newFakeDB(t)
+	db := WithDialect(sqlDB, SQLServer)
+
+	deletedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fake.push(fakeResult{
+		Columns: []string{"id", "deleted_at"},
+		Rows:    [][]driver.Value{{int64(1), nil}},
+	}, fakeResult{
+		Columns: []string{"deleted_at"},
+		Rows:    [][]driver.Value{{deletedAt}},
+	})
+
+	// NULL is loaded as nil
+	var doc Doc
+	if err := Load(db, "doc", &doc, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "SELECT TOP 1 [id],[deleted_at] FROM [doc] WHERE [id]=@p1 AND [deleted_at] IS NULL"
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if doc.ID != 1 || doc.DeletedAt != nil {
+		t.Errorf("doc=%v; wanted loaded row not deleted", doc)
+	}
+
+	if err := Delete(db, "doc", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	want = "UPDATE [doc] SET [deleted_at]=SYSDATETIME() OUTPUT INSERTED.[deleted_at] WHERE [id]=@p1 AND [deleted_at] IS NULL"
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if doc.DeletedAt == nil || !doc.DeletedAt.Equal(deletedAt) {
+		t.Errorf("doc.DeletedAt=%v; wanted %v", doc.DeletedAt, deletedAt)
+	}
+
+	if err := Restore(db, "doc", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want = "UPDATE [doc] SET [deleted_at]=@p1 WHERE [id]=@p2"
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 2 || query.Args[0] != nil {
+		t.Errorf("args=%v; wanted [<nil> 1]", query.Args)
+	}
+
+	if doc.DeletedAt != nil {
+		t.Errorf("doc.DeletedAt=%v; wanted nil", doc.DeletedAt)
+	}
+}
+
+func TestQueryAllSoftDeleteTimePtr(t *testing.T) {
+	type Doc struct {
+		ID        int
+		Name      string
+		DeletedAt *time.Time `sql:"deleted_at,softdelete"`
+	}
+
+	deletedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "name", "deleted_at"},
+		Rows: [][]driver.Value{
+			{int64(1), "a", nil},
+			{int64(2), "b", deletedAt},
+		},
+	})
+
+	var docs []*Doc
+	if err := QueryAll(db, &docs, `SELECT * FROM "doc"`); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(docs) != 2 {
+		t.Fatalf("len(docs)=%v; wanted 2", len(docs))
+	}
+
+	if docs[0].DeletedAt != nil {
+		t.Errorf("docs[0].DeletedAt=%v; wanted nil", docs[0].DeletedAt)
+	}
+
+	if docs[1].DeletedAt == nil || !docs[1].DeletedAt.Equal(deletedAt) {
+		t.Errorf("docs[1].DeletedAt=%v; wanted %v", docs[1].DeletedAt, deletedAt)
+	}
+}
+
+func TestSoftDeleteInvalid(t *testing.T) {
+	type Doc struct {
+		ID        int
+		DeletedAt string `sql:"deleted_at,softdelete"`
+	}
+
+	// time.Time cannot hold NULL, i.e. not deleted
+	type TimeDoc struct {
+		ID        int
+		DeletedAt time.Time `sql:"deleted_at,softdelete"`
+	}
+
+	db, _ := newFakeDB(t)
+	if err := Delete(db, "doc", &Doc{ID: 1}); err == nil {
+		t.Error("Expected error for unsupported softdelete field type")
+	}
+
+	if err := Delete(db, "doc", &TimeDoc{ID: 1}); err == nil {
+		t.Error("Expected error for time.Time softdelete field")
+	}
+}
@@ -0,0 +1,278 @@
+package sqlstruct
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+)
+
+// StmtCache caches the SQL generated by Insert, Update, Delete and Load
+// together with the prepared statements thereof. Databases wrapped using Wrap
+// or WrapTx use the cache. The cache holds at most size statements, evicting
+// the least recently used ones.
+type StmtCache struct {
+	size int
+
+	mu      sync.Mutex
+	lru     *list.List // of *stmtEntry, most recently used first
+	entries map[stmtKey]*list.Element
+}
+
+// sqlKey identifies the SQL generated for an operation on a struct type.
+type sqlKey struct {
+	dialect Dialect
+	typ     reflect.Type
+	table   string
+	op      string
+}
+
+type stmtKey struct {
+	db *sql.DB
+	sqlKey
+}
+
+type stmtEntry struct {
+	key     stmtKey
+	query   string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+// NewStmtCache creates a statement cache holding at most size statements.
+func NewStmtCache(size int) *StmtCache {
+	if size < 1 {
+		size = 1
+	}
+
+	return &StmtCache{
+		size:    size,
+		lru:     list.New(),
+		entries: map[stmtKey]*list.Element{},
+	}
+}
+
+// Wrap returns a DB that uses the cached statements for db. The returned DB
+// also implements DBContext.
+func (c *StmtCache) Wrap(db *sql.DB) DB {
+	return &cachedDB{db, nil, c}
+}
+
+// WrapTx returns a DB that uses the cached statements of db within the
+// transaction tx, which must have been started on db. The statements are
+// re-bound to the transaction using Tx.StmtContext. The returned DB also
+// implements DBContext.
+func (c *StmtCache) WrapTx(db *sql.DB, tx *sql.Tx) DB {
+	return &cachedDB{db, tx, c}
+}
+
+// Len returns the number of cached statements.
+func (c *StmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Close closes and removes all cached statements. Statements currently in use
+// are closed once they are released.
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for c.lru.Len() > 0 {
+		if cerr := c.evict(c.lru.Back()); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// get returns the entry for the given key, preparing its statement if it is
+// not cached yet. The returned entry must be released after use.
+func (c *StmtCache) get(ctx context.Context, key stmtKey, build func() (string, error)) (*stmtEntry, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(el)
+		entry := el.Value.(*stmtEntry)
+		entry.refs++
+		c.mu.Unlock()
+		return entry, nil
+	}
+	c.mu.Unlock()
+
+	query, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	prepared, err := key.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		// prepared concurrently by someone else
+		prepared.Close()
+		c.lru.MoveToFront(el)
+		entry := el.Value.(*stmtEntry)
+		entry.refs++
+		return entry, nil
+	}
+
+	entry := &stmtEntry{key: key, query: query, stmt: prepared, refs: 1}
+	c.entries[key] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.size {
+		c.evict(c.lru.Back())
+	}
+
+	return entry, nil
+}
+
+func (c *StmtCache) release(entry *stmtEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.refs--
+	if entry.evicted && entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// evict removes the given element; its statement is closed as soon as it is
+// not used anymore. Must be called with c.mu held.
+func (c *StmtCache) evict(el *list.Element) error {
+	entry := c.lru.Remove(el).(*stmtEntry)
+	delete(c.entries, entry.key)
+	entry.evicted = true
+	if entry.refs == 0 {
+		return entry.stmt.Close()
+	}
+	return nil
+}
+
+type cachedDB struct {
+	db    *sql.DB
+	tx    *sql.Tx // nil outside of transactions
+	cache *StmtCache
+}
+
+func (db *cachedDB) conn() DBContext {
+	if db.tx != nil {
+		return db.tx
+	}
+	return db.db
+}
+
+func (db *cachedDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.conn().ExecContext(context.Background(), query, args...)
+}
+
+func (db *cachedDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.conn().QueryContext(context.Background(), query, args...)
+}
+
+func (db *cachedDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.conn().QueryRowContext(context.Background(), query, args...)
+}
+
+func (db *cachedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.conn().ExecContext(ctx, query, args...)
+}
+
+func (db *cachedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.conn().QueryContext(ctx, query, args...)
+}
+
+func (db *cachedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.conn().QueryRowContext(ctx, query, args...)
+}
+
+// stmtCacheOf returns the cache wrapper of db, if any.
+func stmtCacheOf(db DBContext) *cachedDB {
+	for {
+		switch v := db.(type) {
+		case *cachedDB:
+			return v
+		case *dialectDB:
+			db = v.db
+		default:
+			return nil
+		}
+	}
+}
+
+// stmt is a statement generated by one of the helpers. It is executed as a
+// prepared statement if db uses a StmtCache, and directly otherwise.
+type stmt struct {
+	db       DBContext
+	query    string
+	prepared *sql.Stmt
+	release  func()
+}
+
+// prepare returns the statement identified by key, whose SQL is built using
+// build unless it is cached already. The statement must be closed after use.
+func prepare(ctx context.Context, db DBContext, key sqlKey, build func() (string, error)) (*stmt, error) {
+	cached := stmtCacheOf(db)
+	if cached == nil {
+		query, err := build()
+		if err != nil {
+			return nil, err
+		}
+		return &stmt{db: db, query: query}, nil
+	}
+
+	entry, err := cached.cache.get(ctx, stmtKey{cached.db, key}, build)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &stmt{db: db, query: entry.query, prepared: entry.stmt}
+	if cached.tx != nil {
+		s.prepared = cached.tx.StmtContext(ctx, entry.stmt)
+	}
+	s.release = func() {
+		if cached.tx != nil {
+			s.prepared.Close()
+		}
+		cached.cache.release(entry)
+	}
+
+	return s, nil
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	if s.prepared != nil {
+		return s.prepared.ExecContext(ctx, args...)
+	}
+	return s.db.ExecContext(ctx, s.query, args...)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	if s.prepared != nil {
+		return s.prepared.QueryContext(ctx, args...)
+	}
+	return s.db.QueryContext(ctx, s.query, args...)
+}
+
+func (s *stmt) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
+	if s.prepared != nil {
+		return s.prepared.QueryRowContext(ctx, args...)
+	}
+	return s.db.QueryRowContext(ctx, s.query, args...)
+}
+
+// Close releases the statement. Rows returned by QueryContext and
+// QueryRowContext must be closed or scanned before.
+func (s *stmt) Close() {
+	if s.release != nil {
+		s.release()
+	}
+}
@@ -0,0 +1,161 @@
+package sqlstruct
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+type stmtUser struct {
+	ID   int
+	Name string
+}
+
+func TestStmtCache(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	cache := NewStmtCache(10)
+	db := cache.Wrap(sqlDB)
+
+	for i := 0; i < 3; i++ {
+		if err := Update(db, "user", &stmtUser{i, "rkusa"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fake.prepared != 1 {
+		t.Errorf("prepared=%v; wanted 1", fake.prepared)
+	}
+
+	if len(fake.queries) != 3 {
+		t.Errorf("len(queries)=%v; wanted 3", len(fake.queries))
+	}
+
+	want := `UPDATE "user" SET "name"=$1 WHERE "id"=$2`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if cache.Len() != 1 {
+		t.Errorf("cache.Len()=%v; wanted 1", cache.Len())
+	}
+}
+
+func TestStmtCacheKeys(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	cache := NewStmtCache(10)
+	db := cache.Wrap(sqlDB)
+
+	user := stmtUser{1, "rkusa"}
+	calls := []func() error{
+		func() error { return Update(db, "user", &user) },
+		func() error { return Update(db, "admin", &user) },
+		func() error { return Delete(db, "user", &user) },
+		func() error { return Update(WithDialect(db, MySQL), "user", &user) },
+		func() error { return Update(db, "user", &user) },
+	}
+
+	for _, call := range calls {
+		if err := call(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fake.prepared != 4 {
+		t.Errorf("prepared=%v; wanted 4", fake.prepared)
+	}
+
+	want := "UPDATE `user` SET `name`=? WHERE `id`=?"
+	if q := fake.queries[3].Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestStmtCacheEviction(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	cache := NewStmtCache(1)
+	db := cache.Wrap(sqlDB)
+
+	user := stmtUser{1, "rkusa"}
+	for i := 0; i < 2; i++ {
+		if err := Update(db, "user", &user); err != nil {
+			t.Fatal(err)
+		}
+		if err := Delete(db, "user", &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fake.prepared != 4 {
+		t.Errorf("prepared=%v; wanted 4", fake.prepared)
+	}
+
+	if cache.Len() != 1 {
+		t.Errorf("cache.Len()=%v; wanted 1", cache.Len())
+	}
+
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if cache.Len() != 0 {
+		t.Errorf("cache.Len()=%v; wanted 0", cache.Len())
+	}
+}
+
+func TestStmtCacheTx(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	cache := NewStmtCache(10)
+
+	fake.push(fakeResult{
+		Columns: []string{"id", "name"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa"}},
+	})
+
+	user := stmtUser{}
+	if err := Load(cache.Wrap(sqlDB), "user", &user, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := sqlDB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.push(fakeResult{
+		Columns: []string{"id", "name"},
+		Rows:    [][]driver.Value{{int64(2), "rkgo"}},
+	})
+
+	if err := Load(cache.WrapTx(sqlDB, tx), "user", &user, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != 2 || user.Name != "rkgo" {
+		t.Errorf("user=%v; wanted {2 rkgo}", user)
+	}
+
+	if cache.Len() != 1 {
+		t.Errorf("cache.Len()=%v; wanted 1", cache.Len())
+	}
+
+	want := `SELECT "id","name" FROM "user" WHERE "id"=$1 LIMIT 1`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func BenchmarkUpdateStmtCache(b *testing.B) {
+	sqlDB, _ := newFakeDB(b)
+	db := NewStmtCache(10).Wrap(sqlDB)
+	user := stmtUser{1, "rkusa"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := Update(db, "user", &user); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 )
 
 const tagName = "sql"
 const pkTag = "pk"
 const readonlyTag = "readonly"
+const uniqueTag = "unique"
+const defaultTag = "default"
+const versionTag = "version"
+const softDeleteTag = "softdelete"
 
 type column struct {
 	Type      reflect.Type
@@ -80,25 +85,144 @@ func ExtractTable(s interface{}) (*Table, error) {
 		return nil, fmt.Errorf("sqlstruct: must be called with a pointer; got %v", t)
 	}
 
-	return fields(reflect.ValueOf(s), false)
+	info, err := structInfoOf(t.Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	return info.bind(reflect.ValueOf(s).Elem()), nil
+}
+
+// structInfo is the reflection data of a struct type, which is extracted once
+// per type and bound to concrete values using bind.
+type structInfo struct {
+	Fields   []*fieldInfo
+	PKs      []int   // indexes into Fields
+	Mapper   *Mapper // generated mapper, if registered
+	Tracking []int   // index sequence of the embedded Tracking, if any
+}
+
+type fieldInfo struct {
+	Index     []int // index sequence for reflect.Value.FieldByIndex
+	Type      reflect.Type
+	Name      string
+	FieldName string
+	Tags      map[string]bool
+	Embedded  bool
+}
+
+var structInfoCache sync.Map // map[reflect.Type]*structInfo
+
+func structInfoOf(t reflect.Type) (*structInfo, error) {
+	if info, ok := structInfoCache.Load(t); ok {
+		return info.(*structInfo), nil
+	}
+
+	info, err := fields(t, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := mappers.Load(t); ok {
+		if err := checkMapper(t, info, m.(*Mapper)); err != nil {
+			return nil, err
+		}
+		info.Mapper = m.(*Mapper)
+	}
+
+	actual, _ := structInfoCache.LoadOrStore(t, info)
+	return actual.(*structInfo), nil
+}
+
+// bind creates the table of the given struct value, which must be of the type
+// the struct info was extracted from. Nil embedded struct pointers are
+// initialized.
+func (info *structInfo) bind(v reflect.Value) *Table {
+	columns := make([]column, len(info.Fields))
+	table := &Table{
+		Columns: make([]*column, len(info.Fields)),
+		PKs:     make([]*column, len(info.PKs)),
+	}
+
+	var targets []interface{}
+	if info.Mapper != nil {
+		targets = info.Mapper.Targets(v.Addr().Interface())
+	}
+
+	for i, f := range info.Fields {
+		var fv reflect.Value
+		if targets != nil {
+			fv = reflect.ValueOf(targets[i]).Elem()
+		} else {
+			fv = f.value(v)
+		}
+
+		typ := f.Type
+		if pf, ok := f.nullable(v); ok {
+			fv, typ = pf, pf.Type()
+		}
+
+		columns[i] = column{typ, fv, f.Name, f.FieldName, f.Tags, f.Embedded}
+		table.Columns[i] = &columns[i]
+	}
+
+	for i, idx := range info.PKs {
+		table.PKs[i] = table.Columns[idx]
+	}
+
+	return table
+}
+
+// value returns the field of the given struct value, dereferencing pointers.
+func (f *fieldInfo) value(v reflect.Value) reflect.Value {
+	v = f.field(v)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	return v
+}
+
+// nullable returns the field of the given struct value if it is a softdelete
+// field of pointer type. Such fields are bound as is, so that NULL, i.e. not
+// deleted, is scanned as nil.
+func (f *fieldInfo) nullable(v reflect.Value) (reflect.Value, bool) {
+	if !f.Tags[softDeleteTag] {
+		return reflect.Value{}, false
+	}
+	pf := f.field(v)
+	return pf, pf.Kind() == reflect.Ptr
 }
 
-// TODO: cache reflection data
-func fields(v reflect.Value, embedded bool) (*Table, error) {
-	t := v.Type()
+// field returns the field of the given struct value, dereferencing embedded
+// struct pointers, but not the field itself.
+func (f *fieldInfo) field(v reflect.Value) reflect.Value {
+	last := len(f.Index) - 1
+	for i, idx := range f.Index {
+		v = v.Field(idx)
+		if i < last && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				// init embedded struct
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+	}
+	return v
+}
 
+func fields(t reflect.Type, embedded bool) (*structInfo, error) {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
-		v = v.Elem()
 	}
 
 	if t.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("sqlstruct: called with pointer to non-struct; got %v", t)
 	}
 
-	table := &Table{}
-	var pkCol *column
-	var embeddedPKs []*column
+	var columns, pks []*fieldInfo
+	var pkCol *fieldInfo
+	var embeddedPKs []*fieldInfo
+	var tracking []int
 
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
@@ -108,23 +232,19 @@ func fields(v reflect.Value, embedded bool) (*Table, error) {
 		}
 
 		ft := f.Type
-		fv := v.Field(i)
 		if ft.Kind() == reflect.Ptr {
 			ft = ft.Elem()
-			fv = fv.Elem()
 		}
 
 		nameTag, tags := stripTag(f)
 
 		// TODO: distinguish between Fields and embeded structs
-		if f.Anonymous { // embedded struct
-			if !fv.IsValid() { // eg. is nil
-				// init embedded struct
-				fv = reflect.New(ft)
-				v.Field(i).Set(fv)
+		if f.Anonymous && ft == trackingType {
+			if tracking == nil {
+				tracking = []int{i}
 			}
-
-			embedded, err := fields(fv, true)
+		} else if f.Anonymous { // embedded struct
+			embedded, err := fields(ft, true)
 			if err != nil {
 				return nil, err
 			}
@@ -137,20 +257,26 @@ func fields(v reflect.Value, embedded bool) (*Table, error) {
 				prefix += "_"
 			}
 
-			for _, c := range embedded.Columns {
+			for _, c := range embedded.Fields {
 				c.Name = prefix + c.Name
+				c.Index = append([]int{i}, c.Index...)
 			}
 
-			table.Columns = append(table.Columns, embedded.Columns...)
+			columns = append(columns, embedded.Fields...)
+			if tracking == nil && embedded.Tracking != nil {
+				tracking = append([]int{i}, embedded.Tracking...)
+			}
 			if embeddedPKs == nil && len(embedded.PKs) != 0 {
-				embeddedPKs = embedded.PKs
+				for _, idx := range embedded.PKs {
+					embeddedPKs = append(embeddedPKs, embedded.Fields[idx])
+				}
 			}
 		} else if nameTag != "-" {
-			c := &column{ft, fv, nameOf(f, nameTag), f.Name, tags, embedded}
-			table.Columns = append(table.Columns, c)
+			c := &fieldInfo{[]int{i}, ft, nameOf(f, nameTag), f.Name, tags, embedded}
+			columns = append(columns, c)
 			_, isPk := tags[pkTag]
 			if isPk {
-				table.PKs = append(table.PKs, c)
+				pks = append(pks, c)
 			}
 
 			if pkCol == nil && f.Name == "ID" {
@@ -159,25 +285,40 @@ func fields(v reflect.Value, embedded bool) (*Table, error) {
 		}
 	}
 
-	if len(table.PKs) == 0 && pkCol != nil {
-		table.PKs = append(table.PKs, pkCol)
+	if len(pks) == 0 && pkCol != nil {
+		pks = append(pks, pkCol)
 	}
 
-	if len(table.PKs) == 0 && len(embeddedPKs) > 0 {
-		table.PKs = append(table.PKs, embeddedPKs...)
+	if len(pks) == 0 && len(embeddedPKs) > 0 {
+		pks = append(pks, embeddedPKs...)
 	}
 
 	if !embedded {
-		if len(table.PKs) == 0 {
+		if len(pks) == 0 {
 			return nil, fmt.Errorf("sqlstruct: no primary key set/found for %v", t)
 		}
 
-		for _, c := range table.PKs {
+		for _, c := range columns {
+			if err := checkIdentifier(c.Name); err != nil {
+				return nil, fmt.Errorf("sqlstruct: invalid column name %q for field %v", c.Name, c.FieldName)
+			}
+		}
+
+		for _, c := range pks {
 			c.Embedded = false
 		}
 	}
 
-	return table, nil
+	info := &structInfo{Fields: columns, Tracking: tracking}
+	for _, pk := range pks {
+		for i, c := range columns {
+			if c == pk {
+				info.PKs = append(info.PKs, i)
+			}
+		}
+	}
+
+	return info, nil
 }
 
 func stripTag(f reflect.StructField) (string, map[string]bool) {
@@ -196,6 +337,21 @@ func stripTag(f reflect.StructField) (string, map[string]bool) {
 	return nameTag, tagMapping
 }
 
+// tagValues returns the values of all occurrences of the given tag, which is
+// either set plainly, e.g. "unique", or with a value, e.g. "unique=email". The
+// value of plain tags is empty.
+func tagValues(tags map[string]bool, name string) []string {
+	var values []string
+	for tag := range tags {
+		if tag == name {
+			values = append(values, "")
+		} else if strings.HasPrefix(tag, name+"=") {
+			values = append(values, tag[len(name)+1:])
+		}
+	}
+	return values
+}
+
 func nameOf(f reflect.StructField, nameTag string) string {
 	if nameTag == "" {
 		return strings.ToLower(f.Name)
@@ -1,6 +1,10 @@
 package sqlstruct
 
-import "testing"
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
 
 func TestIDPK(t *testing.T) {
 	type User struct {
@@ -390,3 +394,116 @@ func TestReadonlyTest(t *testing.T) {
 		t.Errorf("Expected to skip readonly names")
 	}
 }
+
+func TestQuotedNamesEscaping(t *testing.T) {
+	type User struct {
+		ID   int
+		Name string `sql:"first\"name"`
+	}
+
+	table, err := ExtractTable(&User{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := table.QuotedNames(true, true)
+	if names[1] != `"first""name"` {
+		t.Errorf("Name=%v; wanted %v", names[1], `"first""name"`)
+	}
+}
+
+func TestStructInfoCache(t *testing.T) {
+	type User struct {
+		ID   int
+		Name string
+	}
+
+	a, err := ExtractTable(&User{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ExtractTable(&User{ID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.PKs[0].Value.Int() != 1 || b.PKs[0].Value.Int() != 2 {
+		t.Errorf("Tables must be bound to their own values")
+	}
+
+	info1, _ := structInfoOf(reflect.TypeOf(User{}))
+	info2, _ := structInfoOf(reflect.TypeOf(User{}))
+	if info1 != info2 {
+		t.Errorf("Expected struct info to be cached")
+	}
+}
+
+func TestStructInfoCacheConcurrent(t *testing.T) {
+	type User struct {
+		ID   int
+		Name string
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			table, err := ExtractTable(&User{ID: id})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if table.PKs[0].Value.Int() != int64(id) {
+				t.Errorf("PK=%v; wanted %v", table.PKs[0].Value.Int(), id)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestEmbeddedPtrInit(t *testing.T) {
+	type Address struct {
+		City string
+	}
+	type User struct {
+		ID int
+		*Address
+	}
+
+	user := User{}
+	table, err := ExtractTable(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.Address == nil {
+		t.Fatalf("Expected embedded pointer to be initialized")
+	}
+
+	table.Columns[1].Value.SetString("Dresden")
+	if user.City != "Dresden" {
+		t.Errorf("user.City = %v; but want Dresden", user.City)
+	}
+}
+
+func BenchmarkExtractTable(b *testing.B) {
+	type Address struct {
+		Street string
+		City   string
+	}
+	type User struct {
+		ID    int
+		Name  string
+		Email string
+		Address
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ExtractTable(&User{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package sqlstruct
+
+import "reflect"
+
+// Tracking enables dirty tracking when embedded into a struct, either by value
+// or as a pointer:
+//
+//	type User struct {
+//		sqlstruct.Tracking
+//		ID   int
+//		Name string
+//	}
+//
+// Load, QueryRow and the other query helpers then remember the values of the
+// columns they scan, and Update only sets the columns whose values changed
+// since, skipping the statement entirely if nothing changed. Update and
+// UpdateColumns remember the values they write. Structs not loaded from the
+// database, e.g. created using a literal, are updated in full.
+type Tracking struct {
+	loaded map[string]interface{} // column name -> value
+}
+
+var trackingType = reflect.TypeOf(Tracking{})
+
+// trackingOf returns the Tracking embedded into the struct src points to, or
+// nil if there is none.
+func trackingOf(src interface{}) *Tracking {
+	v := reflect.ValueOf(src)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil
+	}
+
+	info, err := structInfoOf(v.Type().Elem())
+	if err != nil {
+		return nil
+	}
+
+	return info.tracking(v.Elem())
+}
+
+// tracking returns the Tracking embedded into the struct value v, which must be
+// of the type the struct info was extracted from, or nil if there is none. Nil
+// embedded pointers, including to the Tracking, are initialized.
+func (info *structInfo) tracking(v reflect.Value) *Tracking {
+	if info.Tracking == nil {
+		return nil
+	}
+
+	for _, idx := range info.Tracking {
+		v = v.Field(idx)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+	}
+	return v.Addr().Interface().(*Tracking)
+}
+
+// snapshot replaces the remembered values by the current values of columns.
+func (t *Tracking) snapshot(columns []*column) {
+	if t == nil {
+		return
+	}
+
+	loaded := make(map[string]interface{}, len(columns))
+	for _, col := range columns {
+		loaded[col.Name] = copyValue(col.Value)
+	}
+	t.loaded = loaded
+}
+
+// remember updates the remembered values of columns, if t is tracking at all.
+// The map is copied rather than updated in place, since copies of the struct
+// share it.
+func (t *Tracking) remember(columns []*column) {
+	if t == nil || t.loaded == nil {
+		return
+	}
+
+	loaded := make(map[string]interface{}, len(t.loaded))
+	for name, value := range t.loaded {
+		loaded[name] = value
+	}
+	for _, col := range columns {
+		loaded[col.Name] = copyValue(col.Value)
+	}
+	t.loaded = loaded
+}
+
+// tracked reports whether t remembers any loaded values.
+func (t *Tracking) tracked() bool {
+	return t != nil && t.loaded != nil
+}
+
+// changed returns the columns whose values differ from the remembered ones.
+// Columns without a remembered value are considered changed.
+func (t *Tracking) changed(columns []*column) []*column {
+	var changed []*column
+	for _, col := range columns {
+		loaded, ok := t.loaded[col.Name]
+		if !ok || !reflect.DeepEqual(loaded, copyValue(col.Value)) {
+			changed = append(changed, col)
+		}
+	}
+	return changed
+}
+
+// copyValue returns the value of v, copying slices, e.g. []byte, so that later
+// modifications in place are detected.
+func copyValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil // nil pointer field
+	}
+	if v.Kind() == reflect.Slice && !v.IsNil() {
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(c, v)
+		return c.Interface()
+	}
+	return v.Interface()
+}
@@ -0,0 +1,146 @@
+package sqlstruct
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+type trackedUser struct {
+	Tracking
+	ID      int
+	Name    string
+	Country string
+	Avatar  []byte
+}
+
+func TestUpdateTracked(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "name", "country", "avatar"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa", "Germany", []byte{1}}},
+	})
+
+	var user trackedUser
+	if err := Load(db, "user", &user, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Update(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 1 {
+		t.Fatalf("len(queries)=%v; wanted 1, nothing changed", len(fake.queries))
+	}
+
+	user.Name = "foo"
+	user.Avatar[0] = 2
+	if err := Update(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `UPDATE "user" SET "name"=$1,"avatar"=$2 WHERE "id"=$3`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 3 || query.Args[0] != "foo" || query.Args[2] != int64(1) {
+		t.Errorf("args=%v; wanted [foo [2] 1]", query.Args)
+	}
+
+	// the written values are remembered
+	if err := Update(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 2 {
+		t.Errorf("len(queries)=%v; wanted 2, nothing changed", len(fake.queries))
+	}
+}
+
+func TestUpdateTrackedCopy(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "name", "country", "avatar"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa", "Germany", nil}},
+	})
+
+	var a trackedUser
+	if err := Load(db, "user", &a, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// copies do not affect the remembered values of each other
+	b := a
+	b.Name = "foo"
+	if err := UpdateColumns(db, "user", &b, []string{"name"}); err != nil {
+		t.Fatal(err)
+	}
+
+	a.Name = "foo"
+	if err := Update(db, "user", &a); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 3 {
+		t.Fatalf("len(queries)=%v; wanted 3, a's name changed", len(fake.queries))
+	}
+
+	want := `UPDATE "user" SET "name"=$1 WHERE "id"=$2`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestUpdateUntracked(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	user := trackedUser{ID: 1, Name: "rkusa"}
+	if err := Update(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `UPDATE "user" SET "name"=$1,"country"=$2,"avatar"=$3 WHERE "id"=$4`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+type TrackedAddress struct {
+	*Tracking
+	City string
+}
+
+func TestUpdateTrackedEmbedded(t *testing.T) {
+	type Customer struct {
+		ID int
+		*TrackedAddress
+		Name string
+	}
+
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "trackedaddress_city", "name"},
+		Rows:    [][]driver.Value{{int64(1), "Berlin", "rkusa"}},
+	})
+
+	var customer Customer
+	if err := QueryRow(db, &customer, `SELECT * FROM customer`); err != nil {
+		t.Fatal(err)
+	}
+
+	if customer.Tracking == nil {
+		t.Fatal("Expected embedded Tracking pointer to be initialized")
+	}
+
+	customer.City = "Munich"
+	if err := Update(db, "customer", &customer); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `UPDATE "customer" SET "trackedaddress_city"=$1 WHERE "id"=$2`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
@@ -0,0 +1,177 @@
+package sqlstruct
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+type updateUser struct {
+	ID       int
+	Name     string
+	Country  string
+	LastSeen int    `sql:"last_seen"`
+	Slug     string `sql:",readonly"`
+}
+
+func TestUpdateColumns(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "slug"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa"}},
+	})
+
+	user := updateUser{ID: 1, Name: "rkusa", Country: "Germany", LastSeen: 42}
+	if err := UpdateColumns(db, "user", &user, []string{"name", "LastSeen"}); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `UPDATE "user" SET "name"=$1,"last_seen"=$2 WHERE "id"=$3 RETURNING "id","slug"`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 3 || query.Args[0] != "rkusa" || query.Args[1] != int64(42) || query.Args[2] != int64(1) {
+		t.Errorf("args=%v; wanted [rkusa 42 1]", query.Args)
+	}
+
+	if user.Slug != "rkusa" {
+		t.Errorf("user.Slug=%v; wanted rkusa", user.Slug)
+	}
+}
+
+func TestUpdateColumnsStmtCache(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	db := NewStmtCache(10).Wrap(sqlDB)
+
+	user := updateUser{ID: 1, Name: "rkusa", Country: "Germany"}
+	for _, cols := range [][]string{{"name"}, {"Name", "name"}, {"country"}} {
+		if err := UpdateColumns(db, "user", &user, cols); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fake.prepared != 2 {
+		t.Errorf("prepared=%v; wanted 2", fake.prepared)
+	}
+
+	want := `UPDATE "user" SET "country"=$1 WHERE "id"=$2 RETURNING "id","slug"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestUpdateColumnsInvalid(t *testing.T) {
+	db, _ := newFakeDB(t)
+
+	user := updateUser{ID: 1}
+	for _, cols := range [][]string{
+		{},
+		{"unknown"},
+		{"id"},
+		{"slug"},
+		{"name", "unknown"},
+	} {
+		if err := UpdateColumns(db, "user", &user, cols); err == nil {
+			t.Errorf("Expected error when updating columns %v", cols)
+		}
+	}
+}
+
+func TestUpdateColumnsOptions(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	db := WithDialect(sqlDB, SQLite)
+	fake.push(fakeResult{RowsAffected: 0})
+
+	var n int64
+	user := updateUser{ID: 1, Name: "rkusa"}
+	err := UpdateColumns(db, "user", &user, []string{"name"}, RowsAffected(&n), MustExist())
+	if err != ErrNotFound {
+		t.Errorf("err=%v; wanted ErrNotFound", err)
+	}
+	if n != 0 {
+		t.Errorf("n=%v; wanted 0", n)
+	}
+
+	fake.push(fakeResult{RowsAffected: 1}, fakeResult{
+		Columns: []string{"id", "name", "country", "last_seen", "slug"},
+		Rows:    [][]driver.Value{{int64(1), "rkusa", "Germany", int64(42), "rkusa"}},
+	})
+
+	if err := UpdateColumns(db, "user", &user, []string{"name"}, ReturnAll()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `SELECT "id","name","country","last_seen","slug" FROM "user" WHERE "id"=? LIMIT 1`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.Country != "Germany" || user.Slug != "rkusa" {
+		t.Errorf("user=%v; wanted reloaded row", user)
+	}
+}
+
+func TestUpdateRowsAffected(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	var n int64
+	if err := Update(db, "user", &stmtUser{1, "rkusa"}, RowsAffected(&n)); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("n=%v; wanted 1", n)
+	}
+
+	fake.push(fakeResult{RowsAffected: 0})
+	if err := Update(db, "user", &stmtUser{2, "rkusa"}, RowsAffected(&n)); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("n=%v; wanted 0", n)
+	}
+}
+
+func TestUpdateMustExist(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{RowsAffected: 0})
+
+	if err := Update(db, "user", &stmtUser{1, "rkusa"}, MustExist()); err != ErrNotFound {
+		t.Errorf("err=%v; wanted ErrNotFound", err)
+	}
+
+	// RETURNING yields no row
+	fake.push(fakeResult{Columns: []string{"id", "slug"}})
+
+	var n int64
+	if err := Update(db, "user", &updateUser{ID: 1}, MustExist(), RowsAffected(&n)); err != ErrNotFound {
+		t.Errorf("err=%v; wanted ErrNotFound", err)
+	}
+	if n != 0 {
+		t.Errorf("n=%v; wanted 0", n)
+	}
+
+	if err := Update(db, "user", &stmtUser{1, "rkusa"}, MustExist()); err != nil {
+		t.Errorf("err=%v; wanted nil", err)
+	}
+}
+
+func TestDeleteRowsAffected(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{RowsAffected: 1}, fakeResult{RowsAffected: 0})
+
+	var n int64
+	if err := Delete(db, "user", &stmtUser{1, "rkusa"}, RowsAffected(&n), MustExist()); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("n=%v; wanted 1", n)
+	}
+
+	if err := Delete(db, "user", &stmtUser{1, "rkusa"}, RowsAffected(&n), MustExist()); err != ErrNotFound {
+		t.Errorf("err=%v; wanted ErrNotFound", err)
+	}
+	if n != 0 {
+		t.Errorf("n=%v; wanted 0", n)
+	}
+}
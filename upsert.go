@@ -0,0 +1,393 @@
+package sqlstruct
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Upsert inserts src, or updates the existing row conflicting with it. The
+// primary keys and readonly columns of the resulting row are read back into
+// src; use ReturnAll to read back all columns.
+//
+// The conflict target is the primary keys, or the unique group given using
+// ConflictOn. All non-primary-key, non-readonly columns inserted are updated
+// on conflict, unless restricted using UpdateOnConflict. The column tagged
+// softdelete is never updated, so soft deleted rows stay deleted.
+//
+// Dialects using ON DUPLICATE KEY UPDATE (MySQL) do not support choosing the
+// conflict target, any unique key conflicts; readonly columns are not read
+// back and generated primary keys are read back using LastInsertId. Other
+// dialects without RETURNING (SQLite) only read back anything if the primary
+// keys are generated or ReturnAll is given, by loading the resulting row by
+// the conflict target using a separate query.
+func Upsert(db DB, tableName string, src interface{}, opts ...Option) error {
+	return UpsertContext(context.Background(), withContext(db), tableName, src, opts...)
+}
+
+// UpsertContext is like Upsert, but uses the given context for the statement.
+func UpsertContext(ctx context.Context, db DBContext, tableName string, src interface{}, opts ...Option) error {
+	o := applyOptions(opts)
+
+	table, err := ExtractTable(src)
+	if err != nil {
+		return err
+	}
+
+	if err := generateIDs(ctx, reflect.TypeOf(src), table); err != nil {
+		return err
+	}
+
+	d := dialectOf(db)
+	if d.Upsert() == UpsertUnsupported {
+		return fmt.Errorf("sqlstruct.Upsert: not supported by dialect %T", d)
+	}
+
+	generated := generatedPKs(table)
+	columns := insertColumns(table)
+	returned := returnedColumns(table, o.returnAll, defaultTag, readonlyTag)
+
+	conflict := table.PKs
+	if o.conflict != "" {
+		conflict = uniqueGroup(table, o.conflict)
+		if len(conflict) == 0 {
+			return fmt.Errorf("sqlstruct.Upsert: unknown unique group %q", o.conflict)
+		}
+	} else if len(generated) > 0 && d.Upsert() == UpsertOnConflict {
+		return fmt.Errorf("sqlstruct.Upsert: primary keys must be set to be used as conflict target")
+	}
+
+	// omitted columns are left untouched, and so is the softdelete column,
+	// which is only changed by Delete and Restore
+	var update []*column
+	for _, col := range updatableColumns(table) {
+		if findColumn(columns, col.Name) != nil {
+			update = append(update, col)
+		}
+	}
+	if o.update != nil {
+		update = nil
+		for _, name := range o.update {
+			col := findColumn(updatableColumns(table), name)
+			if col == nil {
+				return fmt.Errorf("sqlstruct.Upsert: unknown or non-updatable column %q", name)
+			}
+			update = append(update, col)
+		}
+	}
+
+	if len(update) == 0 {
+		return fmt.Errorf("sqlstruct.Upsert: no columns to update")
+	}
+
+	// LastInsertId does not report the key of updated rows with ON CONFLICT,
+	// the resulting row is loaded by the conflict target instead
+	var readBack *column
+	var lookupValues []interface{}
+	if d.InsertID() == InsertIDLastInsertID {
+		if d.Upsert() == UpsertOnDuplicateKey {
+			if readBack, err = lastInsertIDColumn(table); err != nil {
+				return fmt.Errorf("sqlstruct.Upsert: %v", err)
+			}
+		} else if o.returnAll || len(generated) > 0 {
+			for _, col := range conflict {
+				lookupValues = append(lookupValues, col.Value.Interface())
+			}
+		}
+	}
+
+	op := "upsert:" + strings.Join(columnNames(columns), ",") + ":" + strings.Join(columnNames(conflict), ",") + ":" + strings.Join(columnNames(update), ",")
+	if o.returnAll {
+		op += "-all"
+	}
+	k := sqlKey{d, reflect.TypeOf(src), tableName, op}
+	stmt, err := prepare(ctx, db, k, func() (string, error) {
+		return upsertSQL(d, tableName, columns, readBack, conflict, update, returned)
+	})
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	values := columnAddrs(columns)
+
+	if d.InsertID() == InsertIDReturning {
+		return stmt.QueryRowContext(ctx, values...).Scan(columnAddrs(returned)...)
+	}
+
+	res, err := stmt.ExecContext(ctx, values...)
+	if err != nil {
+		return err
+	}
+
+	if readBack != nil {
+		return setLastInsertID(readBack, res)
+	}
+
+	if lookupValues != nil {
+		return loadBy(ctx, db, tableName, reflect.TypeOf(src), table, conflict, lookupValues)
+	}
+
+	return nil
+}
+
+// loadBy loads all columns of the row of table, which was extracted from a
+// struct of type typ, having the given values in the lookup columns.
+func loadBy(ctx context.Context, db DBContext, tableName string, typ reflect.Type, table *Table, lookup []*column, values []interface{}) error {
+	d := dialectOf(db)
+	k := sqlKey{d, typ, tableName, "load-by:" + strings.Join(columnNames(lookup), ",")}
+	stmt, err := prepare(ctx, db, k, func() (string, error) {
+		return selectSQL(d, tableName, table, lookup)
+	})
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.QueryRowContext(ctx, values...).Scan(table.Values(true, true)...)
+}
+
+// uniqueGroup returns the columns of the given unique group.
+func uniqueGroup(table *Table, group string) []*column {
+	var columns []*column
+	for _, col := range table.Columns {
+		for _, name := range tagValues(col.Tags, uniqueTag) {
+			if name == group || name == "" && col.Name == group {
+				columns = append(columns, col)
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// findColumn returns the column with the given column or field name, if any.
+func findColumn(columns []*column, name string) *column {
+	for _, col := range columns {
+		if col.Name == name || col.FieldName == name {
+			return col
+		}
+	}
+	return nil
+}
+
+func upsertSQL(d Dialect, tableName string, columns []*column, readBack *column, conflict, update, returned []*column) (string, error) {
+	insert, params, err := insertClauses(d, tableName, columns, 1)
+	if err != nil {
+		return "", err
+	}
+	query := insert + params
+
+	pairs := make([]string, 0, len(update)+1)
+	switch d.Upsert() {
+	case UpsertOnConflict:
+		for _, col := range update {
+			name := d.Quote(col.Name)
+			pairs = append(pairs, name+"=EXCLUDED."+name)
+		}
+		query += fmt.Sprintf(
+			" ON CONFLICT (%s) DO UPDATE SET %s",
+			strings.Join(quoteAll(d, columnNames(conflict)), ","),
+			strings.Join(pairs, ","),
+		)
+	case UpsertOnDuplicateKey:
+		if readBack != nil {
+			// make LastInsertId report the key of the updated row
+			pk := d.Quote(readBack.Name)
+			pairs = append(pairs, pk+"=LAST_INSERT_ID("+pk+")")
+		}
+		for _, col := range update {
+			name := d.Quote(col.Name)
+			pairs = append(pairs, name+"=VALUES("+name+")")
+		}
+		query += " ON DUPLICATE KEY UPDATE " + strings.Join(pairs, ",")
+	default:
+		return "", fmt.Errorf("sqlstruct.Upsert: unsupported upsert strategy %v", d.Upsert())
+	}
+
+	if d.InsertID() == InsertIDReturning {
+		query += returningClause(d, returned)
+	}
+
+	return query, nil
+}
+
+// InsertIgnore inserts src unless it conflicts with an existing row, and
+// reports whether it was inserted. Columns are omitted and read back into src
+// like with Insert. Use ConflictOn to only ignore conflicts of a specific
+// unique group, and LoadExisting to load the conflicting row into src.
+//
+// Dialects using ON DUPLICATE KEY UPDATE (MySQL) use INSERT IGNORE, which
+// ignores conflicts of any unique key regardless of ConflictOn.
+func InsertIgnore(db DB, tableName string, src interface{}, opts ...Option) (bool, error) {
+	return InsertIgnoreContext(context.Background(), withContext(db), tableName, src, opts...)
+}
+
+// InsertIgnoreContext is like InsertIgnore, but uses the given context for the
+// statements.
+func InsertIgnoreContext(ctx context.Context, db DBContext, tableName string, src interface{}, opts ...Option) (bool, error) {
+	o := applyOptions(opts)
+
+	table, err := ExtractTable(src)
+	if err != nil {
+		return false, err
+	}
+
+	if err := generateIDs(ctx, reflect.TypeOf(src), table); err != nil {
+		return false, err
+	}
+
+	d := dialectOf(db)
+	if d.Upsert() == UpsertUnsupported {
+		return false, fmt.Errorf("sqlstruct.InsertIgnore: not supported by dialect %T", d)
+	}
+
+	columns := insertColumns(table)
+	returned := returnedColumns(table, o.returnAll, defaultTag, readonlyTag)
+
+	var conflict []*column
+	if o.conflict != "" {
+		conflict = uniqueGroup(table, o.conflict)
+		if len(conflict) == 0 {
+			return false, fmt.Errorf("sqlstruct.InsertIgnore: unknown unique group %q", o.conflict)
+		}
+	}
+
+	lookup := conflict
+	if o.loadExisting && lookup == nil {
+		if len(generatedPKs(table)) > 0 {
+			return false, fmt.Errorf("sqlstruct.InsertIgnore: primary keys must be set or a unique group given to load the existing row")
+		}
+		lookup = table.PKs
+	}
+
+	var readBack *column
+	if d.InsertID() == InsertIDLastInsertID {
+		if readBack, err = lastInsertIDColumn(table); err != nil {
+			return false, fmt.Errorf("sqlstruct.InsertIgnore: %v", err)
+		}
+	}
+
+	// remember the lookup values, scanning the returned keys may change them
+	var lookupValues []interface{}
+	for _, col := range lookup {
+		lookupValues = append(lookupValues, col.Value.Interface())
+	}
+
+	op := "insert-ignore:" + strings.Join(columnNames(columns), ",") + ":" + strings.Join(columnNames(conflict), ",")
+	if o.returnAll {
+		op += "-all"
+	}
+	k := sqlKey{d, reflect.TypeOf(src), tableName, op}
+	stmt, err := prepare(ctx, db, k, func() (string, error) {
+		return insertIgnoreSQL(d, tableName, columns, conflict, returned)
+	})
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	values := columnAddrs(columns)
+
+	inserted := true
+	if d.InsertID() == InsertIDReturning {
+		err := stmt.QueryRowContext(ctx, values...).Scan(columnAddrs(returned)...)
+		if err == sql.ErrNoRows {
+			// nothing is returned for rows that were not inserted
+			inserted = false
+		} else if err != nil {
+			return false, err
+		}
+	} else {
+		res, err := stmt.ExecContext(ctx, values...)
+		if err != nil {
+			return false, err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+		inserted = affected > 0
+
+		if inserted && readBack != nil {
+			if err := setLastInsertID(readBack, res); err != nil {
+				return false, err
+			}
+		}
+
+		if inserted && o.returnAll && len(returned) > len(table.PKs) {
+			if err := reload(ctx, db, tableName, reflect.TypeOf(src), table); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if inserted || !o.loadExisting {
+		return inserted, nil
+	}
+
+	if err := loadBy(ctx, db, tableName, reflect.TypeOf(src), table, lookup, lookupValues); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+func insertIgnoreSQL(d Dialect, tableName string, columns, conflict, returned []*column) (string, error) {
+	insert, params, err := insertClauses(d, tableName, columns, 1)
+	if err != nil {
+		return "", err
+	}
+
+	switch d.Upsert() {
+	case UpsertOnConflict:
+		query := insert + params + " ON CONFLICT"
+		if len(conflict) > 0 {
+			query += " (" + strings.Join(quoteAll(d, columnNames(conflict)), ",") + ")"
+		}
+		query += " DO NOTHING"
+
+		if d.InsertID() == InsertIDReturning {
+			query += returningClause(d, returned)
+		}
+		return query, nil
+	case UpsertOnDuplicateKey:
+		return "INSERT IGNORE" + strings.TrimPrefix(insert, "INSERT") + params, nil
+	default:
+		return "", fmt.Errorf("sqlstruct.InsertIgnore: unsupported upsert strategy %v", d.Upsert())
+	}
+}
+
+// selectSQL builds a query loading a single row of table by the given columns.
+func selectSQL(d Dialect, tableName string, table *Table, where []*column) (string, error) {
+	quotedTable, err := quoteTable(d, tableName)
+	if err != nil {
+		return "", err
+	}
+
+	top, limit := d.Limit(1)
+	if top != "" {
+		top += " "
+	}
+
+	conditions := make([]string, len(where))
+	for i, col := range where {
+		conditions[i] = d.Quote(col.Name) + "=" + d.Placeholder(1+i)
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s%s FROM %s WHERE %s",
+		top,
+		strings.Join(quoteAll(d, table.Names(true, true)), ","),
+		quotedTable,
+		strings.Join(conditions, " AND "),
+	)
+	if limit != "" {
+		query += " " + limit
+	}
+
+	return query, nil
+}
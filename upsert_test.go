@@ -0,0 +1,295 @@
+package sqlstruct
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+type upsertUser struct {
+	ID       int
+	TenantID int    `sql:"tenant_id,unique=tenant_email"`
+	Email    string `sql:",unique=tenant_email"`
+	Name     string
+	Login    string    `sql:",unique"`
+	Created  time.Time `sql:",readonly"`
+}
+
+func TestUpsert(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake.push(fakeResult{
+		Columns: []string{"id", "created"},
+		Rows:    [][]driver.Value{{int64(1), created}},
+	})
+
+	user := upsertUser{ID: 1, TenantID: 2, Email: "a@b.c", Name: "rkusa"}
+	if err := Upsert(db, "user", &user); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `INSERT INTO "user" ("id","tenant_id","email","name","login") VALUES ($1,$2,$3,$4,$5)` +
+		` ON CONFLICT ("id") DO UPDATE SET "tenant_id"=EXCLUDED."tenant_id","email"=EXCLUDED."email","name"=EXCLUDED."name","login"=EXCLUDED."login"` +
+		` RETURNING "id","created"`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 5 {
+		t.Errorf("args=%v; wanted 5", query.Args)
+	}
+
+	if !user.Created.Equal(created) {
+		t.Errorf("user.Created=%v; wanted %v", user.Created, created)
+	}
+}
+
+func TestUpsertUniqueGroup(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "created"},
+		Rows:    [][]driver.Value{{int64(42), time.Now()}},
+	})
+
+	user := upsertUser{TenantID: 2, Email: "a@b.c", Name: "rkusa"}
+	if err := Upsert(db, "user", &user, ConflictOn("tenant_email"), UpdateOnConflict("Name", "login")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INSERT INTO "user" ("tenant_id","email","name","login") VALUES ($1,$2,$3,$4)` +
+		` ON CONFLICT ("tenant_id","email") DO UPDATE SET "name"=EXCLUDED."name","login"=EXCLUDED."login"` +
+		` RETURNING "id","created"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.ID != 42 {
+		t.Errorf("user.ID=%v; wanted 42", user.ID)
+	}
+}
+
+func TestUpsertPlainUnique(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "created"},
+		Rows:    [][]driver.Value{{int64(1), time.Now()}},
+	})
+
+	user := upsertUser{Login: "rkusa"}
+	if err := Upsert(db, "user", &user, ConflictOn("login"), UpdateOnConflict("name")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INSERT INTO "user" ("tenant_id","email","name","login") VALUES ($1,$2,$3,$4)` +
+		` ON CONFLICT ("login") DO UPDATE SET "name"=EXCLUDED."name"` +
+		` RETURNING "id","created"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestUpsertMySQL(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	fake.push(fakeResult{LastInsertID: 7, RowsAffected: 2})
+
+	user := upsertUser{Email: "a@b.c", Name: "rkusa"}
+	if err := Upsert(WithDialect(sqlDB, MySQL), "user", &user, UpdateOnConflict("name")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "INSERT INTO `user` (`tenant_id`,`email`,`name`,`login`) VALUES (?,?,?,?)" +
+		" ON DUPLICATE KEY UPDATE `id`=LAST_INSERT_ID(`id`),`name`=VALUES(`name`)"
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if user.ID != 7 {
+		t.Errorf("user.ID=%v; wanted 7", user.ID)
+	}
+}
+
+func TestUpsertSQLite(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake.push(fakeResult{}, fakeResult{
+		Columns: []string{"id", "tenant_id", "email", "name", "login", "created"},
+		Rows:    [][]driver.Value{{int64(7), int64(2), "a@b.c", "rkusa", "", created}},
+	})
+
+	user := upsertUser{TenantID: 2, Email: "a@b.c", Name: "rkusa"}
+	if err := Upsert(WithDialect(sqlDB, SQLite), "user", &user, ConflictOn("tenant_email")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 2 {
+		t.Fatalf("len(queries)=%v; wanted 2", len(fake.queries))
+	}
+
+	want := `INSERT INTO "user" ("tenant_id","email","name","login") VALUES (?,?,?,?)` +
+		` ON CONFLICT ("tenant_id","email") DO UPDATE SET "tenant_id"=EXCLUDED."tenant_id","email"=EXCLUDED."email","name"=EXCLUDED."name","login"=EXCLUDED."login"`
+	if q := fake.queries[0].Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	query := fake.last(t)
+	want = `SELECT "id","tenant_id","email","name","login","created" FROM "user" WHERE "tenant_id"=? AND "email"=? LIMIT 1`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 2 || query.Args[0] != int64(2) || query.Args[1] != "a@b.c" {
+		t.Errorf("args=%v; wanted [2 a@b.c]", query.Args)
+	}
+
+	if user.ID != 7 || !user.Created.Equal(created) {
+		t.Errorf("user=%v; wanted key and readonly columns to be read back", user)
+	}
+}
+
+func TestUpsertErrors(t *testing.T) {
+	sqlDB, _ := newFakeDB(t)
+
+	tests := []struct {
+		name string
+		db   DB
+		user upsertUser
+		opts []Option
+	}{
+		{"unknown group", sqlDB, upsertUser{ID: 1}, []Option{ConflictOn("unknown")}},
+		{"unknown column", sqlDB, upsertUser{ID: 1}, []Option{UpdateOnConflict("unknown")}},
+		{"readonly column", sqlDB, upsertUser{ID: 1}, []Option{UpdateOnConflict("created")}},
+		{"no columns", sqlDB, upsertUser{ID: 1}, []Option{UpdateOnConflict()}},
+		{"pk not set", sqlDB, upsertUser{}, nil},
+		{"unsupported dialect", WithDialect(sqlDB, SQLServer), upsertUser{ID: 1}, nil},
+	}
+
+	for _, test := range tests {
+		if err := Upsert(test.db, "user", &test.user, test.opts...); err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+}
+
+func TestInsertIgnore(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "created"},
+		Rows:    [][]driver.Value{{int64(5), time.Now()}},
+	})
+
+	user := upsertUser{Login: "rkusa"}
+	inserted, err := InsertIgnore(db, "user", &user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !inserted {
+		t.Error("inserted=false; wanted true")
+	}
+
+	if user.ID != 5 {
+		t.Errorf("user.ID=%v; wanted 5", user.ID)
+	}
+
+	want := `INSERT INTO "user" ("tenant_id","email","name","login") VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING RETURNING "id","created"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestInsertIgnoreConflict(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(returnedIDs())
+
+	user := upsertUser{Login: "rkusa"}
+	inserted, err := InsertIgnore(db, "user", &user, ConflictOn("login"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inserted {
+		t.Error("inserted=true; wanted false")
+	}
+
+	want := `INSERT INTO "user" ("tenant_id","email","name","login") VALUES ($1,$2,$3,$4) ON CONFLICT ("login") DO NOTHING RETURNING "id","created"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestInsertIgnoreLoadExisting(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(returnedIDs(), fakeResult{
+		Columns: []string{"id", "tenant_id", "email", "name", "login", "created"},
+		Rows:    [][]driver.Value{{int64(3), int64(2), "a@b.c", "existing", "rkusa", time.Now()}},
+	})
+
+	user := upsertUser{TenantID: 2, Email: "a@b.c", Name: "new"}
+	inserted, err := InsertIgnore(db, "user", &user, ConflictOn("tenant_email"), LoadExisting())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inserted {
+		t.Error("inserted=true; wanted false")
+	}
+
+	if user.ID != 3 || user.Name != "existing" {
+		t.Errorf("user=%v; wanted existing row", user)
+	}
+
+	query := fake.last(t)
+	want := `SELECT "id","tenant_id","email","name","login","created" FROM "user" WHERE "tenant_id"=$1 AND "email"=$2 LIMIT 1`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 2 || query.Args[0] != int64(2) || query.Args[1] != "a@b.c" {
+		t.Errorf("args=%v; wanted [2 a@b.c]", query.Args)
+	}
+}
+
+func TestInsertIgnoreMySQL(t *testing.T) {
+	sqlDB, fake := newFakeDB(t)
+	db := WithDialect(sqlDB, MySQL)
+
+	fake.push(fakeResult{LastInsertID: 9, RowsAffected: 1}, fakeResult{
+		Columns: []string{"id", "tenant_id", "email", "name", "login", "created"},
+		Rows:    [][]driver.Value{{int64(9), int64(0), "", "", "rkusa", time.Now()}},
+	})
+	user := upsertUser{Login: "rkusa"}
+	inserted, err := InsertIgnore(db, "user", &user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !inserted || user.ID != 9 {
+		t.Errorf("inserted=%v, user.ID=%v; wanted true, 9", inserted, user.ID)
+	}
+
+	want := "INSERT IGNORE INTO `user` (`tenant_id`,`email`,`name`,`login`) VALUES (?,?,?,?)"
+	if q := fake.queries[0].Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	fake.push(fakeResult{RowsAffected: 0})
+	user = upsertUser{Login: "rkusa"}
+	inserted, err = InsertIgnore(db, "user", &user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inserted || user.ID != 0 {
+		t.Errorf("inserted=%v, user.ID=%v; wanted false, 0", inserted, user.ID)
+	}
+}
+
+func TestInsertIgnoreLoadExistingWithoutKey(t *testing.T) {
+	db, _ := newFakeDB(t)
+
+	if _, err := InsertIgnore(db, "user", &upsertUser{}, LoadExisting()); err == nil {
+		t.Error("Expected error when loading existing row without key")
+	}
+}
@@ -0,0 +1,168 @@
+package sqlstruct
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+type versionedDoc struct {
+	ID      int
+	Name    string
+	Version int `sql:",version"`
+}
+
+func TestUpdateVersion(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	doc := versionedDoc{ID: 1, Name: "a", Version: 3}
+	if err := Update(db, "doc", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `UPDATE "doc" SET "name"=$1,"version"=$2 WHERE "id"=$3 AND "version"=$4`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 4 || query.Args[1] != int64(4) || query.Args[2] != int64(1) || query.Args[3] != int64(3) {
+		t.Errorf("args=%v; wanted [a 4 1 3]", query.Args)
+	}
+
+	if doc.Version != 4 {
+		t.Errorf("doc.Version=%v; wanted 4", doc.Version)
+	}
+}
+
+func TestUpdateVersionStale(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{RowsAffected: 0})
+
+	doc := versionedDoc{ID: 1, Name: "a", Version: 3}
+	if err := Update(db, "doc", &doc); err != ErrStaleObject {
+		t.Errorf("err=%v; wanted ErrStaleObject", err)
+	}
+
+	if doc.Version != 3 {
+		t.Errorf("doc.Version=%v; wanted 3", doc.Version)
+	}
+}
+
+func TestUpdateVersionReturning(t *testing.T) {
+	type Doc struct {
+		ID      int
+		Name    string
+		Version uint   `sql:",version"`
+		Updated string `sql:",readonly"`
+	}
+
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "updated"},
+		Rows:    [][]driver.Value{{int64(1), "now"}},
+	}, fakeResult{
+		Columns: []string{"id", "updated"},
+	})
+
+	doc := Doc{ID: 1, Name: "a"}
+	if err := Update(db, "doc", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `UPDATE "doc" SET "name"=$1,"version"=$2 WHERE "id"=$3 AND "version"=$4 RETURNING "id","updated"`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+
+	if doc.Version != 1 || doc.Updated != "now" {
+		t.Errorf("doc=%v; wanted version 1 and updated to be read back", doc)
+	}
+
+	if err := Update(db, "doc", &doc); err != ErrStaleObject {
+		t.Errorf("err=%v; wanted ErrStaleObject", err)
+	}
+}
+
+func TestUpdateColumnsVersion(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	doc := versionedDoc{ID: 1, Name: "a", Version: 3}
+	if err := UpdateColumns(db, "doc", &doc, []string{"name"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `UPDATE "doc" SET "name"=$1,"version"=$2 WHERE "id"=$3 AND "version"=$4`
+	if q := fake.last(t).Query; q != want {
+		t.Errorf("query=%v; wanted %v", q, want)
+	}
+}
+
+func TestUpdateColumnsVersionTracked(t *testing.T) {
+	type Doc struct {
+		Tracking
+		ID      int
+		Name    string
+		Version int `sql:",version"`
+	}
+
+	db, fake := newFakeDB(t)
+	fake.push(fakeResult{
+		Columns: []string{"id", "name", "version"},
+		Rows:    [][]driver.Value{{int64(1), "a", int64(3)}},
+	})
+
+	var doc Doc
+	if err := Load(db, "doc", &doc, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	doc.Name = "b"
+	if err := UpdateColumns(db, "doc", &doc, []string{"name"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the incremented version is remembered as well
+	if err := Update(db, "doc", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.queries) != 2 {
+		t.Errorf("len(queries)=%v; wanted 2, nothing changed", len(fake.queries))
+	}
+}
+
+func TestDeleteVersion(t *testing.T) {
+	db, fake := newFakeDB(t)
+
+	doc := versionedDoc{ID: 1, Version: 3}
+	if err := Delete(db, "doc", &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.last(t)
+	want := `DELETE FROM "doc" WHERE "id"=$1 AND "version"=$2`
+	if query.Query != want {
+		t.Errorf("query=%v; wanted %v", query.Query, want)
+	}
+
+	if len(query.Args) != 2 || query.Args[1] != int64(3) {
+		t.Errorf("args=%v; wanted [1 3]", query.Args)
+	}
+
+	fake.push(fakeResult{RowsAffected: 0})
+	if err := Delete(db, "doc", &doc); err != ErrStaleObject {
+		t.Errorf("err=%v; wanted ErrStaleObject", err)
+	}
+}
+
+func TestVersionInvalid(t *testing.T) {
+	type Doc struct {
+		ID      int
+		Version string `sql:",version"`
+	}
+
+	db, _ := newFakeDB(t)
+	if err := Update(db, "doc", &Doc{ID: 1}); err == nil {
+		t.Error("Expected error for non-integer version")
+	}
+}